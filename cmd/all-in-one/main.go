@@ -0,0 +1,75 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The all-in-one binary runs the controller and the webhook of the sample
+// source in a single process, sharing one leader election configuration and
+// one metrics endpoint.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	filteredinformerfactory "knative.dev/pkg/client/injection/kube/informers/factory/filtered"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/injection/sharedmain"
+	"knative.dev/pkg/signals"
+	"knative.dev/pkg/webhook"
+
+	"knative.dev/sample-source/pkg/reconciler/sample"
+	"knative.dev/sample-source/pkg/reconciler/sample/resources"
+	"knative.dev/sample-source/pkg/version"
+	samplewebhook "knative.dev/sample-source/pkg/webhook"
+)
+
+const component = "sample-source"
+
+var (
+	enableController = flag.Bool("controller", true, "Whether to run the SampleSource controller.")
+	enableWebhook    = flag.Bool("webhook", true, "Whether to run the admission webhooks.")
+)
+
+func main() {
+	if err := version.SetMinimumVersion(); err != nil {
+		log.Fatal("Error setting the minimum Kubernetes version: ", err)
+	}
+	// This parses flags, so the above are set once this runs.
+	cfg := injection.ParseAndGetRESTConfigOrDie()
+
+	ctx, ctors := setup(signals.NewContext(), *enableController, *enableWebhook)
+	if len(ctors) == 0 {
+		log.Fatal("At least one of --controller and --webhook must be enabled")
+	}
+	sharedmain.MainWithConfig(ctx, component, cfg, ctors...)
+}
+
+// setup returns the context and the constructors of the enabled components.
+func setup(ctx context.Context, controller, admission bool) (context.Context, []injection.ControllerConstructor) {
+	// Only cache the child resources created by the controller. The selectors
+	// are set even when the controller is disabled: linking it registers the
+	// filtered informers, which cannot be set up without them.
+	ctx = filteredinformerfactory.WithSelectors(ctx, resources.LabelSelector)
+
+	var ctors []injection.ControllerConstructor
+	if controller {
+		ctors = append(ctors, sample.NewController)
+	}
+	if admission {
+		ctx = webhook.WithOptions(ctx, samplewebhook.Options())
+		ctors = append(ctors, samplewebhook.Constructors()...)
+	}
+	return ctx, ctors
+}
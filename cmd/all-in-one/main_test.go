@@ -0,0 +1,177 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	cminformer "knative.dev/pkg/configmap/informer"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/injection/sharedmain"
+	"knative.dev/pkg/logging"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/profiling"
+	"knative.dev/pkg/system"
+	"knative.dev/pkg/tracing/config"
+	"knative.dev/pkg/webhook"
+
+	samplewebhook "knative.dev/sample-source/pkg/webhook"
+
+	// Fake injection clients and informers
+	_ "knative.dev/pkg/client/injection/kube/client/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/admissionregistration/v1/mutatingwebhookconfiguration/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/admissionregistration/v1/validatingwebhookconfiguration/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment/filtered/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/factory/filtered/fake"
+	_ "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	_ "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
+	_ "knative.dev/pkg/system/testing"
+	_ "knative.dev/sample-source/pkg/client/injection/informers/samples/v1alpha1/samplesource/fake"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := map[string]struct {
+		controller, admission bool
+		wantCtors             int
+	}{
+		"both": {
+			controller: true,
+			admission:  true,
+			wantCtors:  1 + len(samplewebhook.Constructors()),
+		},
+		"controller only": {
+			controller: true,
+			wantCtors:  1,
+		},
+		"webhook only": {
+			admission: true,
+			wantCtors: len(samplewebhook.Constructors()),
+		},
+		"none": {},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ctx, ctors := setup(context.Background(), tc.controller, tc.admission)
+			if len(ctors) != tc.wantCtors {
+				t.Errorf("constructors = %d, want %d", len(ctors), tc.wantCtors)
+			}
+
+			// The webhook is only served by sharedmain when its options are set.
+			if got := webhook.GetOptions(ctx) != nil; got != tc.admission {
+				t.Errorf("webhook options set = %v, want %v", got, tc.admission)
+			}
+
+			// Every informer linked into the binary is set up by sharedmain,
+			// whichever components are enabled.
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("SetupInformers() panicked: %v", r)
+					}
+				}()
+				injection.Default.SetupInformers(ctx, &rest.Config{Host: "http://127.0.0.1:0"})
+			}()
+		})
+	}
+}
+
+// freePort returns a port no listener is bound to.
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() = %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// TestServers boots the webhook and profiling servers of the all-in-one
+// binary the way sharedmain does, and checks that they come up on distinct
+// ports.
+func TestServers(t *testing.T) {
+	webhookPort, profilingPort := freePort(t), freePort(t)
+	os.Setenv("PROFILING_PORT", fmt.Sprint(profilingPort))
+	defer os.Unsetenv("PROFILING_PORT")
+	os.Setenv("METRICS_DOMAIN", "knative.dev/eventing")
+	os.Setenv("SAMPLE_SOURCE_RA_IMAGE", "knative.dev/example")
+
+	ctx, cancel := context.WithCancel(logtesting.TestContextWithLogger(t))
+	defer cancel()
+	ctx, ctors := setup(ctx, true, true)
+	webhook.GetOptions(ctx).Port = webhookPort
+	ctx, informers := injection.Fake.SetupInformers(ctx, &rest.Config{})
+	ctx = addressable.WithDuck(ctx)
+
+	for _, name := range []string{metrics.ConfigMapName(), logging.ConfigMapName(), config.ConfigName} {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: system.Namespace()}}
+		if _, err := kubeclient.Get(ctx).CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Create(%s) = %v", name, err)
+		}
+	}
+	cmw := cminformer.NewInformedWatcher(kubeclient.Get(ctx), system.Namespace())
+	controllers, webhooks := sharedmain.ControllersAndWebhooksFromCtors(ctx, cmw, ctors...)
+	if len(controllers) != len(ctors) {
+		t.Errorf("controllers = %d, want %d", len(controllers), len(ctors))
+	}
+	if len(webhooks) == 0 {
+		t.Fatal("no admission controllers")
+	}
+
+	wh, err := webhook.New(ctx, webhooks)
+	if err != nil {
+		t.Fatalf("webhook.New() = %v", err)
+	}
+	go wh.Run(ctx.Done())
+	profilingServer := profiling.NewServer(profiling.NewHandler(logging.FromContext(ctx), false))
+	go profilingServer.ListenAndServe()
+	defer profilingServer.Shutdown(context.Background())
+	for _, informer := range informers {
+		go informer.Run(ctx.Done())
+	}
+
+	if profilingServer.Addr != fmt.Sprint(":", profilingPort) {
+		t.Errorf("profiling server address = %q, want port %d", profilingServer.Addr, profilingPort)
+	}
+	for name, port := range map[string]int{"webhook": webhookPort, "profiling": profilingPort} {
+		addr := fmt.Sprint("127.0.0.1:", port)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s server is not listening on %s: %v", name, addr, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
@@ -17,13 +17,25 @@ limitations under the License.
 package main
 
 import (
+	"log"
+
 	// The set of controllers this controller process runs.
 	"knative.dev/sample-source/pkg/reconciler/sample"
+	"knative.dev/sample-source/pkg/reconciler/sample/resources"
+	"knative.dev/sample-source/pkg/version"
 
 	// This defines the shared main for injected controllers.
 	"knative.dev/pkg/injection/sharedmain"
+
+	filteredinformerfactory "knative.dev/pkg/client/injection/kube/informers/factory/filtered"
+	"knative.dev/pkg/signals"
 )
 
 func main() {
-	sharedmain.Main("sample-source-controller", sample.NewController)
+	if err := version.SetMinimumVersion(); err != nil {
+		log.Fatal("Error setting the minimum Kubernetes version: ", err)
+	}
+	// Only cache the child resources created by this controller.
+	ctx := filteredinformerfactory.WithSelectors(signals.NewContext(), resources.LabelSelector)
+	sharedmain.MainWithContext(ctx, "sample-source-controller", sample.NewController)
 }
@@ -16,9 +16,14 @@ package main
 
 import (
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
+
 	myadapter "knative.dev/sample-source/pkg/adapter"
 )
 
 func main() {
-	adapter.Main("sample-source", myadapter.NewEnv, myadapter.NewAdapter)
+	// Injection provides the Kubernetes client the /version endpoint
+	// reports the cluster version with.
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+	adapter.MainWithContext(ctx, "sample-source", myadapter.NewEnv, myadapter.NewAdapter)
 }
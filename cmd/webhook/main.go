@@ -16,119 +16,24 @@ limitations under the License.
 package main
 
 import (
-	"context"
-	"fmt"
+	"log"
 
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"knative.dev/pkg/configmap"
-	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection/sharedmain"
-	"knative.dev/pkg/logging"
-	"knative.dev/pkg/metrics"
 	"knative.dev/pkg/signals"
-	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
-	"knative.dev/pkg/webhook/certificates"
-	"knative.dev/pkg/webhook/configmaps"
-	"knative.dev/pkg/webhook/resourcesemantics"
-	"knative.dev/pkg/webhook/resourcesemantics/defaulting"
-	"knative.dev/pkg/webhook/resourcesemantics/validation"
 
-	"knative.dev/sample-source/pkg/apis/samples/v1alpha1"
+	"knative.dev/sample-source/pkg/version"
+	samplewebhook "knative.dev/sample-source/pkg/webhook"
 )
 
-var types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
-	// List the types to validate
-	v1alpha1.SchemeGroupVersion.WithKind("SampleSource"): &v1alpha1.SampleSource{},
-}
-
-var callbacks = map[schema.GroupVersionKind]validation.Callback{}
-
-const admissionWebhookName = "sample-source-webhook"
-
-// NewDefaultingAdmissionController sets up mutating webhook.
-func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-	return defaulting.NewAdmissionController(ctx,
-
-		// Name of the resource webhook.
-		fmt.Sprintf("defaulting.webhook.%s.knative.dev", system.Namespace()),
-
-		// The path on which to serve the webhook.
-		"/defaulting",
-
-		// The resource to default.
-		types,
-
-		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			// Here is where you would infuse the context with state
-			// (e.g. attach a store with configmap data)
-			return ctx
-		},
-
-		// Whether to disallow unknown fields.
-		true,
-	)
-}
-
-// NewValidationAdmissionController sets up validation webhook.
-func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-	return validation.NewAdmissionController(ctx,
-
-		// Name of the resource webhook.
-		fmt.Sprintf("validation.webhook.%s.knative.dev", system.Namespace()),
-
-		// The path on which to serve the webhook.
-		"/resource-validation",
-
-		// The resources to validate.
-		types,
-
-		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			// Here is where you would infuse the context with state
-			// (e.g. attach a store with configmap data)
-			return ctx
-		},
-
-		// Whether to disallow unknown fields.
-		true,
-
-		// Extra validating callbacks to be applied to resources.
-		callbacks,
-	)
-}
-
-// NewConfigValidationController sets up ConfigMap validation webhook.
-func NewConfigValidationController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-	return configmaps.NewAdmissionController(ctx,
-
-		// Name of the configmap webhook.
-		fmt.Sprintf("config.webhook.%s.knative.dev", system.Namespace()),
-
-		// The path on which to serve the webhook.
-		"/config-validation",
-
-		// The configmaps to validate.
-		configmap.Constructors{
-			logging.ConfigMapName(): logging.NewConfigFromConfigMap,
-			metrics.ConfigMapName(): metrics.NewObservabilityConfigFromConfigMap,
-		},
-	)
-}
-
 func main() {
+	if err := version.SetMinimumVersion(); err != nil {
+		log.Fatal("Error setting the minimum Kubernetes version: ", err)
+	}
 	// Set up a signal context with our webhook options
-	ctx := webhook.WithOptions(signals.NewContext(), webhook.Options{
-		ServiceName: admissionWebhookName,
-		Port:        8443,
-		SecretName:  "webhook-certs",
-	})
+	ctx := webhook.WithOptions(signals.NewContext(), samplewebhook.Options())
 
-	sharedmain.WebhookMainWithContext(ctx, admissionWebhookName,
-		certificates.NewController,
-		NewDefaultingAdmissionController,
-		NewValidationAdmissionController,
-		NewConfigValidationController,
+	sharedmain.WebhookMainWithContext(ctx, samplewebhook.AdmissionWebhookName,
+		samplewebhook.Constructors()...,
 	)
 }
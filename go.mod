@@ -5,8 +5,10 @@ go 1.14
 require (
 	github.com/cloudevents/sdk-go/v2 v2.2.0
 	github.com/google/go-cmp v0.5.5
+	github.com/google/uuid v1.2.0
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/stretchr/testify v1.6.1
+	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.16.0
 	k8s.io/api v0.19.7
 	k8s.io/apimachinery v0.19.7
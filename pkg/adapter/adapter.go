@@ -17,13 +17,21 @@ package adapter
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"k8s.io/client-go/kubernetes"
 
 	"knative.dev/eventing/pkg/adapter/v2"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/source"
 )
 
 type envConfig struct {
@@ -32,6 +40,71 @@ type envConfig struct {
 
 	// Interval between events, for example "5s", "100ms"
 	Interval time.Duration `envconfig:"INTERVAL" required:"true"`
+
+	// SigningSecret is the shared secret used to sign the body of each
+	// event sent to the sink. Signing is disabled if unset.
+	SigningSecret string `envconfig:"SIGNING_SECRET"`
+
+	// SigningSecretFile is the path of a file holding the signing secret.
+	// It takes precedence over SigningSecret.
+	SigningSecretFile string `envconfig:"SIGNING_SECRET_FILE"`
+
+	// SignatureHeader is the header carrying the body signature.
+	SignatureHeader string `envconfig:"SIGNATURE_HEADER" default:"X-Signature-256"`
+
+	// Compression enables gzip compression of event bodies.
+	Compression bool `envconfig:"COMPRESSION"`
+
+	// CompressionThreshold is the body size in bytes above which bodies are
+	// compressed.
+	CompressionThreshold int `envconfig:"COMPRESSION_THRESHOLD" default:"1024"`
+
+	// CompressionAssumeSupported compresses bodies without waiting for the
+	// sink to advertise gzip support.
+	CompressionAssumeSupported bool `envconfig:"COMPRESSION_ASSUME_SUPPORTED"`
+
+	// RecentEventsSize is the number of recently sent events whose id is
+	// retained for lookup on the /events endpoint. Disabled if zero.
+	RecentEventsSize int `envconfig:"RECENT_EVENTS_SIZE"`
+
+	// RecentEventsMaxAge is the maximum age of a retained event id.
+	RecentEventsMaxAge time.Duration `envconfig:"RECENT_EVENTS_MAX_AGE" default:"1h"`
+
+	// SinkFailurePolicy is the handling of events not acknowledged by the
+	// sink, one of "FailFast", "Buffer" or "Fallback".
+	SinkFailurePolicy string `envconfig:"SINK_FAILURE_POLICY" default:"FailFast"`
+
+	// SinkBufferSize is the maximum number of events kept for retry by the
+	// Buffer policy.
+	SinkBufferSize int `envconfig:"SINK_BUFFER_SIZE" default:"100"`
+
+	// SinkDrainTimeout bounds how long the buffered events are retried on
+	// shutdown, for example while the Deployment rolls out a new sink, before
+	// they are sent to the fallback URI.
+	SinkDrainTimeout time.Duration `envconfig:"SINK_DRAIN_TIMEOUT" default:"5s"`
+
+	// SinkFallbackURI is where the Fallback policy sends events to, and
+	// where the events left over by the drain are sent to.
+	SinkFallbackURI string `envconfig:"SINK_FALLBACK_URI"`
+
+	// FailurePolicy is the handling of signing and auditing failures, one
+	// of "Open" or "Closed".
+	FailurePolicy string `envconfig:"FAILURE_POLICY" default:"Open"`
+
+	// InvalidEventPolicy is the handling of events which are not valid
+	// CloudEvents, one of "Drop" or "Fallback".
+	InvalidEventPolicy string `envconfig:"INVALID_EVENT_POLICY" default:"Drop"`
+
+	// AuditSink is where audit events are sent to. Auditing is disabled if
+	// unset.
+	AuditSink string `envconfig:"AUDIT_SINK"`
+
+	// SLOTarget is the time from the creation of an event within which it
+	// should reach the sink or the fallback URI.
+	SLOTarget time.Duration `envconfig:"SLO_TARGET" default:"30s"`
+
+	// HTTPPort is the port the adapter HTTP endpoints are served on.
+	HTTPPort int `envconfig:"HTTP_PORT" default:"8080"`
 }
 
 func NewEnv() adapter.EnvConfigAccessor { return &envConfig{} }
@@ -41,10 +114,45 @@ type Adapter struct {
 	client   cloudevents.Client
 	interval time.Duration
 	logger   *zap.SugaredLogger
+	sink     string
+
+	// failClosed withholds events while signing or auditing fails.
+	failClosed bool
+	// signing is nil unless signing is enabled.
+	signing            *signingTransport
+	sinkFailurePolicy  string
+	invalidEventPolicy string
+	fallbackURI        string
+	bufferSize         int
+	// buffer holds the events to retry with the Buffer policy, oldest first.
+	buffer []bufferedEvent
+	// drainTimeout bounds the retries of buffer on shutdown.
+	drainTimeout time.Duration
+
+	// recent is nil unless the retention of recent events is enabled.
+	recent *recentEvents
+	// auditor is nil unless auditing is enabled.
+	auditor *auditor
+	// stopAuditor stops the auditor, once started.
+	stopAuditor context.CancelFunc
+	// slo accounts for the final outcome of events.
+	slo *sloTracker
+	// server serves the adapter HTTP endpoints.
+	server *http.Server
+	// startup runs the startup work of Start once the HTTP server listens.
+	// The HTTP endpoints answer 503 until it returns.
+	startup func(ctx context.Context)
+	// started is set once startup has returned, accessed atomically.
+	started int32
 
 	nextID int
 }
 
+const (
+	eventType   = "dev.knative.sample"
+	eventSource = "sample.knative.dev/heartbeat-source"
+)
+
 type dataExample struct {
 	Sequence  int    `json:"sequence"`
 	Heartbeat string `json:"heartbeat"`
@@ -52,8 +160,11 @@ type dataExample struct {
 
 func (a *Adapter) newEvent() cloudevents.Event {
 	event := cloudevents.NewEvent()
-	event.SetType("dev.knative.sample")
-	event.SetSource("sample.knative.dev/heartbeat-source")
+	event.SetID(uuid.New().String())
+	event.SetType(eventType)
+	event.SetSource(eventSource)
+	event.SetTime(time.Now())
+	event.SetExtension(versionExtension, Version)
 
 	if err := event.SetData(cloudevents.ApplicationJSON, &dataExample{
 		Sequence:  a.nextID,
@@ -69,30 +180,240 @@ func (a *Adapter) newEvent() cloudevents.Event {
 // Returns if ctx is cancelled or Send() returns an error.
 func (a *Adapter) Start(ctx context.Context) error {
 	a.logger.Infow("Starting heartbeat", zap.String("interval", a.interval.String()))
+	// The listener is bound first, so that probes during startup get a 503
+	// rather than a refused connection.
+	if ln, err := net.Listen("tcp", a.server.Addr); err != nil {
+		a.logger.Errorw("HTTP server failed", zap.Error(err))
+	} else {
+		go a.serve(ctx, ln)
+	}
+	a.startup(ctx)
+	atomic.StoreInt32(&a.started, 1)
+	go a.slo.run(ctx)
 	for {
 		select {
 		case <-time.After(a.interval):
-			event := a.newEvent()
-			a.logger.Infow("Sending new event", zap.String("event", event.String()))
-			if result := a.client.Send(context.Background(), event); !cloudevents.IsACK(result) {
-				a.logger.Infow("failed to send event", zap.String("event", event.String()), zap.Error(result))
-				// We got an error but it could be transient, try again next interval.
-				continue
-			}
+			a.tick(context.Background())
 		case <-ctx.Done():
 			a.logger.Info("Shutting down...")
+			a.drain()
+			a.stopAudit()
 			return nil
 		}
 	}
 }
 
+// tick reads the signing secret again, retries the buffered events, if any,
+// then sends a new event.
+func (a *Adapter) tick(ctx context.Context) {
+	a.reloadSigningSecret()
+	a.flush(ctx)
+	a.send(ctx, a.newEvent())
+}
+
 func NewAdapter(ctx context.Context, aEnv adapter.EnvConfigAccessor, ceClient cloudevents.Client) adapter.Adapter {
 	env := aEnv.(*envConfig) // Will always be our own envConfig type
 	logger := logging.FromContext(ctx)
 	logger.Infow("Heartbeat example", zap.Duration("interval", env.Interval))
-	return &Adapter{
+
+	failClosed := env.FailurePolicy == failurePolicyClosed
+	var signing *signingTransport
+	if env.SigningSecret != "" || env.SigningSecretFile != "" {
+		signing = &signingTransport{
+			base:       http.DefaultTransport,
+			header:     env.SignatureHeader,
+			load:       func() ([]byte, error) { return signingSecret(env) },
+			failClosed: failClosed,
+		}
+		if err := signing.reload(); err != nil {
+			if failClosed {
+				logger.Errorw("Error reading the signing secret, withholding events", zap.Error(err))
+			} else {
+				logger.Errorw("Error reading the signing secret, sending unsigned events", zap.Error(err))
+			}
+		}
+	}
+	if signing != nil || env.Compression {
+		// Bodies are compressed before they are signed, so that the signature
+		// covers the bytes the sink receives.
+		rt := http.DefaultTransport
+		if signing != nil {
+			rt = signing
+		}
+		if env.Compression {
+			rt = newCompressionTransport(rt, env.CompressionThreshold, env.CompressionAssumeSupported)
+		}
+		reporter, err := source.NewStatsReporter()
+		if err != nil {
+			logger.Errorw("Error building statsreporter", zap.Error(err))
+		}
+		c, err := newClient(env, rt, reporter, newCRStatusEventClient(env))
+		if err != nil {
+			logger.Fatalw("Error building cloud event client", zap.Error(err))
+		}
+		ceClient = c
+	}
+	a := &Adapter{
 		interval: env.Interval,
 		client:   ceClient,
 		logger:   logger,
+		sink:     env.Sink,
+
+		failClosed:         failClosed,
+		signing:            signing,
+		sinkFailurePolicy:  env.SinkFailurePolicy,
+		invalidEventPolicy: env.InvalidEventPolicy,
+		fallbackURI:        env.SinkFallbackURI,
+		bufferSize:         env.SinkBufferSize,
+		drainTimeout:       env.SinkDrainTimeout,
+		slo:                newSLOTracker(env.SLOTarget, logger),
+	}
+	if a.sinkFailurePolicy == policyFallback && a.fallbackURI == "" {
+		logger.Fatal("Fallback sink failure policy requires a fallback URI")
 	}
+	if a.invalidEventPolicy == invalidEventFallback && a.fallbackURI == "" {
+		logger.Fatal("Fallback invalid event policy requires a fallback URI")
+	}
+	if a.sinkFailurePolicy == policyBuffer && a.bufferSize <= 0 {
+		logger.Fatal("Buffer sink failure policy requires a positive buffer size")
+	}
+	// Events withheld when failing closed are kept in the buffer, whatever
+	// the sink failure policy.
+	if failClosed && (signing != nil || env.AuditSink != "") && a.bufferSize <= 0 {
+		logger.Fatal("Closed failure policy requires a positive buffer size")
+	}
+
+	if env.AuditSink != "" {
+		a.auditor = newAuditor(ceClient, env.AuditSink, failClosed, logger)
+	}
+
+	mux := http.NewServeMux()
+	vh := &versionHandler{logger: logger}
+	// The Kubernetes client is only available when the adapter runs with
+	// injection enabled.
+	if kc, ok := ctx.Value(kubeclient.Key{}).(kubernetes.Interface); ok {
+		vh.kube = kc.Discovery()
+	}
+	mux.Handle("/version", vh)
+	mux.HandleFunc("/readyz", a.serveReady)
+	if env.RecentEventsSize > 0 {
+		a.recent = newRecentEvents(env.RecentEventsSize, env.RecentEventsMaxAge)
+		mux.Handle("/events", a.recent)
+	}
+	a.server = &http.Server{Addr: fmt.Sprintf(":%d", env.HTTPPort), Handler: newRecoverer(a.whenStarted(mux), logger)}
+	a.startup = a.startAuditor
+	return a
+}
+
+// startAuditor starts sending the audit events, if auditing is enabled. The
+// auditor is not stopped with ctx, so that the outcomes of the events drained
+// on shutdown are audited too, but it stops waiting for room in the queue as
+// soon as ctx is done, so that a send blocked on auditing does not hold up
+// the shutdown.
+func (a *Adapter) startAuditor(ctx context.Context) {
+	if a.auditor != nil {
+		var auditCtx context.Context
+		auditCtx, a.stopAuditor = context.WithCancel(context.Background())
+		context.AfterFunc(ctx, a.auditor.shutdown)
+		go a.auditor.run(auditCtx)
+	}
+}
+
+// stopAudit sends the queued audit events for at most the flush timeout of
+// the auditor, then stops it and drops the audit events left.
+func (a *Adapter) stopAudit() {
+	if a.stopAuditor == nil {
+		return
+	}
+	a.auditor.flush(a.auditor.flushTimeout)
+	a.stopAuditor()
+	<-a.auditor.stopped
+}
+
+// record keeps track of the result of sending event to sink, if the
+// retention of recent events is enabled.
+func (a *Adapter) record(event cloudevents.Event, result cloudevents.Result, sink string) {
+	if cloudevents.IsACK(result) {
+		a.recordStatus(event, statusDelivered, sink, "")
+		return
+	}
+	a.recordStatus(event, statusFailed, sink, failureReason(result))
+}
+
+// recordStatus is record for a given status, and the reason of a failure.
+func (a *Adapter) recordStatus(event cloudevents.Event, status, sink, reason string) {
+	if a.recent == nil {
+		return
+	}
+	a.recent.add(eventRecord{
+		ID:     event.ID(),
+		Time:   time.Now(),
+		Status: status,
+		Sink:   sink,
+		Reason: reason,
+	})
+}
+
+// finish accounts for the final outcome of event, reached after attempts
+// sends, and audits it, if enabled. sink is where event was last sent, and
+// reason why it was not delivered to the sink.
+func (a *Adapter) finish(event cloudevents.Event, outcome, sink, reason string, attempts int) {
+	a.slo.record(event, outcome)
+	if a.auditor != nil {
+		a.auditor.audit(event, auditData{
+			ID:        event.ID(),
+			Type:      event.Type(),
+			EventTime: event.Time(),
+			Outcome:   outcome,
+			Reason:    reason,
+			Sink:      sink,
+			Attempts:  attempts,
+			Time:      time.Now(),
+		})
+	}
+}
+
+// failureReason describes why the sink did not acknowledge an event with
+// result.
+func failureReason(result cloudevents.Result) string {
+	if result == nil {
+		return "not acknowledged"
+	}
+	return result.Error()
+}
+
+// reloadSigningSecret reads the signing secret again, if signing is enabled.
+func (a *Adapter) reloadSigningSecret() {
+	if a.signing == nil {
+		return
+	}
+	wasReady := a.signing.ready()
+	err := a.signing.reload()
+	switch {
+	case err != nil && wasReady:
+		a.logger.Errorw("Error reading the signing secret", zap.Error(err))
+	case err == nil && !wasReady:
+		a.logger.Info("Read the signing secret")
+	}
+}
+
+// degradedDependency returns the dependency events are withheld for when
+// failing closed, empty if there is none.
+func (a *Adapter) degradedDependency() string {
+	if !a.failClosed {
+		return ""
+	}
+	if a.signing != nil && !a.signing.ready() {
+		return "signing secret"
+	}
+	if a.auditor != nil && a.auditor.isDegraded() {
+		return "audit sink"
+	}
+	return ""
+}
+
+// degraded reports whether events are withheld because signing or auditing
+// fails.
+func (a *Adapter) degraded() bool {
+	return a.degradedDependency() != ""
 }
@@ -56,6 +56,7 @@ func verify(t *testing.T, received chan cloudevents.Event) {
 	for _, id := range []int{0, 1, 2} {
 		e := <-received
 		assert.Equal(t, "dev.knative.sample", e.Type())
+		assert.Equal(t, Version, e.Extensions()[versionExtension])
 		//m := map[string]json.RawMessage{}
 		m := &dataExample{}
 		assert.NoError(t, e.DataAs(&m))
@@ -83,6 +84,7 @@ func TestAdapterMain(t *testing.T) {
 		t.Name()+"=main",
 		"K_SINK="+sink.URL(),
 		"INTERVAL="+"1ms",
+		"HTTP_PORT=0",
 		"NAMESPACE=namespace",
 		"NAME=name",
 		`K_METRICS_CONFIG={"domain":"x", "component":"x", "prometheusport":0, "configmap":{}}`,
@@ -135,3 +137,12 @@ func newSink(t *testing.T) *sink {
 }
 
 func (s *sink) URL() string { return "http://" + s.listener.Addr().String() }
+
+// newTestClient returns a CloudEvents client sending to target.
+func newTestClient(t *testing.T, target string) cloudevents.Client {
+	tr, err := cloudevents.NewHTTP(cloudevents.WithTarget(target))
+	require.NoError(t, err)
+	c, err := cloudevents.NewClient(tr, cloudevents.WithUUIDs())
+	require.NoError(t, err)
+	return c
+}
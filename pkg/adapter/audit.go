@@ -0,0 +1,241 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/google/uuid"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+
+	"knative.dev/pkg/metrics"
+)
+
+const (
+	// auditEventType is the type of the audit events.
+	auditEventType = "dev.knative.sample.audit"
+	// auditQueueSize bounds the number of audit events waiting to be sent.
+	auditQueueSize = 100
+	// auditRetryInterval is the delay before resending an audit event when
+	// failing closed.
+	auditRetryInterval = time.Second
+	// auditFlushTimeout bounds how long the queued audit events are sent on
+	// shutdown.
+	auditFlushTimeout = 5 * time.Second
+)
+
+// auditDroppedM counts the audit events which could not be queued or sent.
+var auditDroppedM = stats.Int64(
+	"audit_events_dropped",
+	"Number of audit events dropped",
+	stats.UnitDimensionless,
+)
+
+// auditData is the data of an audit event. It describes the final outcome of
+// an event, without the data of the event itself.
+type auditData struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// EventTime is the time of the event.
+	EventTime time.Time `json:"eventTime"`
+	// Outcome is one of the final outcomes of the SLO metric: delivered,
+	// deadlettered, dropped or lost.
+	Outcome string `json:"outcome"`
+	// Reason is why the event was not delivered to the sink.
+	Reason string `json:"reason,omitempty"`
+	// Sink is where the event was last sent, empty if it was never sent.
+	Sink string `json:"sink"`
+	// Attempts is the number of times the event was sent, to the sink and
+	// to the fallback URI.
+	Attempts int `json:"attempts"`
+	// Time is the time of the outcome.
+	Time time.Time `json:"time"`
+}
+
+// auditor sends audit events to the audit sink. Auditing is best-effort: the
+// audit events are queued and sent asynchronously, and dropped when the
+// queue is full or the audit sink does not acknowledge them.
+//
+// When failing closed, audit events are not dropped: audit waits for room in
+// the full queue, and audit events the audit sink does not acknowledge are
+// retried. The auditor is degraded until one is acknowledged.
+type auditor struct {
+	client     cloudevents.Client
+	target     string
+	failClosed bool
+	logger     *zap.SugaredLogger
+
+	queue chan cloudevents.Event
+	// stopping is closed once the adapter shuts down.
+	stopping chan struct{}
+	// stopped is closed once run has returned.
+	stopped chan struct{}
+	// retryInterval is the delay before resending an audit event when
+	// failing closed.
+	retryInterval time.Duration
+	// flushTimeout bounds how long the queued audit events are sent on
+	// shutdown.
+	flushTimeout time.Duration
+	// pending is the number of audit events queued or being sent, accessed
+	// atomically.
+	pending int64
+	// dropped is the number of audit events dropped, accessed atomically.
+	dropped int64
+	// degraded is 1 while audit events fail, accessed atomically.
+	degraded int32
+}
+
+func newAuditor(client cloudevents.Client, target string, failClosed bool, logger *zap.SugaredLogger) *auditor {
+	if err := view.Register(&view.View{
+		Description: auditDroppedM.Description(),
+		Measure:     auditDroppedM,
+		Aggregation: view.Count(),
+	}); err != nil {
+		logger.Errorw("Error registering the audit metrics view", zap.Error(err))
+	}
+	return &auditor{
+		client:        client,
+		target:        target,
+		failClosed:    failClosed,
+		logger:        logger,
+		queue:         make(chan cloudevents.Event, auditQueueSize),
+		stopping:      make(chan struct{}),
+		stopped:       make(chan struct{}),
+		retryInterval: auditRetryInterval,
+		flushTimeout:  auditFlushTimeout,
+	}
+}
+
+// audit queues an audit event with data d for the final outcome of event. It
+// only blocks when failing closed, while the queue is full.
+func (au *auditor) audit(event cloudevents.Event, d auditData) {
+	ae := cloudevents.NewEvent()
+	ae.SetID(uuid.New().String())
+	ae.SetType(auditEventType)
+	ae.SetSource(event.Source())
+	if err := ae.SetData(cloudevents.ApplicationJSON, &d); err != nil {
+		au.logger.Errorw("failed to set audit data", zap.Error(err))
+		return
+	}
+
+	select {
+	case <-au.stopped:
+		au.drop("auditor stopped", d.ID)
+		return
+	default:
+	}
+	atomic.AddInt64(&au.pending, 1)
+	select {
+	case au.queue <- ae:
+		return
+	default:
+	}
+	if !au.failClosed {
+		atomic.AddInt64(&au.pending, -1)
+		au.drop("queue full", d.ID)
+		return
+	}
+	// The event is already sent, so its audit event is kept even if the
+	// caller has to wait, which withholds the next events. The caller does
+	// not wait once the adapter shuts down, so that shutdown is bounded.
+	atomic.StoreInt32(&au.degraded, 1)
+	select {
+	case au.queue <- ae:
+	case <-au.stopping:
+		atomic.AddInt64(&au.pending, -1)
+		au.drop("queue full on shutdown", d.ID)
+	case <-au.stopped:
+		atomic.AddInt64(&au.pending, -1)
+		au.drop("auditor stopped", d.ID)
+	}
+}
+
+// shutdown makes audit stop waiting for room in the full queue.
+func (au *auditor) shutdown() {
+	close(au.stopping)
+}
+
+// run sends the queued audit events until ctx is cancelled, then drops the
+// audit events still queued.
+func (au *auditor) run(ctx context.Context) {
+	defer close(au.stopped)
+	ctx = cecontext.WithTarget(ctx, au.target)
+	for {
+		select {
+		case ae := <-au.queue:
+			au.send(ctx, ae)
+			atomic.AddInt64(&au.pending, -1)
+		case <-ctx.Done():
+			for {
+				select {
+				case ae := <-au.queue:
+					atomic.AddInt64(&au.pending, -1)
+					au.drop("auditor stopped", ae.ID())
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush waits at most timeout for the queued audit events to be sent.
+func (au *auditor) flush(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&au.pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// send sends ae, retrying it until it is acknowledged when failing closed.
+// It drops ae if ctx is cancelled first.
+func (au *auditor) send(ctx context.Context, ae cloudevents.Event) {
+	for {
+		result := au.client.Send(ctx, ae)
+		if cloudevents.IsACK(result) {
+			atomic.StoreInt32(&au.degraded, 0)
+			return
+		}
+		if !au.failClosed {
+			au.drop(result.Error(), ae.ID())
+			return
+		}
+
+		atomic.StoreInt32(&au.degraded, 1)
+		au.logger.Warnw("failed to send audit event, retrying", zap.String("id", ae.ID()), zap.Error(result))
+		select {
+		case <-time.After(au.retryInterval):
+		case <-ctx.Done():
+			au.drop("auditor stopped", ae.ID())
+			return
+		}
+	}
+}
+
+// isDegraded reports whether audit events fail when failing closed.
+func (au *auditor) isDegraded() bool {
+	return atomic.LoadInt32(&au.degraded) == 1
+}
+
+func (au *auditor) drop(reason, id string) {
+	n := atomic.AddInt64(&au.dropped, 1)
+	metrics.Record(context.Background(), auditDroppedM.M(1))
+	au.logger.Warnw("Dropped audit event", zap.String("id", id), zap.String("reason", reason), zap.Int64("dropped", n))
+}
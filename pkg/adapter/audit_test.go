@@ -0,0 +1,396 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+func TestAdapterAudit(t *testing.T) {
+	// wantAudit is the audit data expected for an event.
+	type wantAudit struct {
+		outcome  string
+		fallback bool
+		reason   bool
+		attempts int
+	}
+
+	testCases := map[string]struct {
+		policy string
+		// want is the audit data of events 0 to 2, sent while the sink is
+		// unready.
+		want []wantAudit
+	}{
+		"fail fast": {
+			policy: policyFailFast,
+			want: []wantAudit{
+				{outcome: outcomeDropped, reason: true, attempts: 1},
+				{outcome: outcomeDropped, reason: true, attempts: 1},
+				{outcome: outcomeDropped, reason: true, attempts: 1},
+			},
+		},
+		"buffer": {
+			// Event 0 is retried before 1 and 2 are sent, then all three
+			// once the sink has recovered.
+			policy: policyBuffer,
+			want: []wantAudit{
+				{outcome: outcomeDelivered, attempts: 4},
+				{outcome: outcomeDelivered, attempts: 1},
+				{outcome: outcomeDelivered, attempts: 1},
+			},
+		},
+		"fallback": {
+			policy: policyFallback,
+			want: []wantAudit{
+				{outcome: outcomeDeadlettered, fallback: true, reason: true, attempts: 2},
+				{outcome: outcomeDeadlettered, fallback: true, reason: true, attempts: 2},
+				{outcome: outcomeDeadlettered, fallback: true, reason: true, attempts: 2},
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := newFlakySink()
+			defer sink.Close()
+			sink.setReady(false)
+			fallback := newFlakySink()
+			defer fallback.Close()
+			audit := newSink(t)
+			defer audit.close()
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+				Interval:          time.Millisecond,
+				SinkFailurePolicy: tc.policy,
+				SinkBufferSize:    10,
+				SinkFallbackURI:   fallback.URL,
+				AuditSink:         audit.URL(),
+			}, newTestClient(t, sink.URL)).(*Adapter)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go a.auditor.run(ctx)
+
+			for i := 0; i < 3; i++ {
+				a.tick(ctx)
+			}
+			sink.setReady(true)
+			a.flush(ctx)
+
+			// There is one audit event per event, for its final outcome.
+			for _, want := range tc.want {
+				e, ok := <-audit.received
+				require.True(t, ok, "timed out waiting for audit event")
+				assert.Equal(t, auditEventType, e.Type())
+
+				d := &auditData{}
+				require.NoError(t, e.DataAs(d))
+				assert.NotEmpty(t, d.ID)
+				assert.Equal(t, "dev.knative.sample", d.Type)
+				assert.Equal(t, want.outcome, d.Outcome)
+				assert.Equal(t, want.reason, d.Reason != "", "reason = %q", d.Reason)
+				assert.Equal(t, want.attempts, d.Attempts)
+				if want.fallback {
+					assert.Equal(t, fallback.URL, d.Sink)
+				} else {
+					assert.Equal(t, sink.URL, d.Sink)
+				}
+				assert.False(t, d.EventTime.IsZero(), "event time must be set")
+				assert.False(t, d.EventTime.After(d.Time), "event time %v after outcome time %v", d.EventTime, d.Time)
+			}
+			select {
+			case e, ok := <-audit.received:
+				if ok {
+					t.Errorf("unexpected audit event %v", e)
+				}
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestAuditorDropsWhenFull(t *testing.T) {
+	au := &auditor{
+		logger: zap.NewNop().Sugar(),
+		queue:  make(chan cloudevents.Event, 1),
+	}
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+
+	au.audit(event, auditData{ID: "1", Outcome: outcomeDelivered})
+	au.audit(event, auditData{ID: "1", Outcome: outcomeDelivered})
+
+	assert.Len(t, au.queue, 1)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&au.dropped))
+}
+
+func TestAuditorWaitsWhenFullFailingClosed(t *testing.T) {
+	au := &auditor{
+		failClosed: true,
+		logger:     zap.NewNop().Sugar(),
+		queue:      make(chan cloudevents.Event, 1),
+		stopping:   make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+
+	au.audit(event, auditData{ID: "1", Outcome: outcomeDelivered})
+	queued := make(chan struct{})
+	go func() {
+		defer close(queued)
+		au.audit(event, auditData{ID: "2", Outcome: outcomeDelivered})
+	}()
+
+	// The second audit event waits for room in the queue.
+	require.Eventually(t, au.isDegraded, 5*time.Second, time.Millisecond)
+	select {
+	case <-queued:
+		t.Fatal("audit returned while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-au.queue
+	<-queued
+	d := &auditData{}
+	require.NoError(t, (<-au.queue).DataAs(d))
+	assert.Equal(t, "2", d.ID)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&au.dropped))
+
+	// Once the adapter shuts down, audit does not wait for room anymore.
+	au.audit(event, auditData{ID: "3", Outcome: outcomeDelivered})
+	au.shutdown()
+	au.audit(event, auditData{ID: "4", Outcome: outcomeDelivered})
+	assert.Equal(t, int64(1), atomic.LoadInt64(&au.dropped))
+	<-au.queue
+
+	// Once the auditor has stopped, audit events cannot be kept.
+	close(au.stopped)
+	au.audit(event, auditData{ID: "5", Outcome: outcomeDelivered})
+	assert.Equal(t, int64(2), atomic.LoadInt64(&au.dropped))
+}
+
+func TestAuditorShutdown(t *testing.T) {
+	testCases := map[string]struct {
+		failClosed bool
+		// hangs makes the audit sink never answer.
+		hangs        bool
+		wantReceived int
+		wantDropped  int64
+	}{
+		"queued audit events are flushed": {
+			wantReceived: 3,
+		},
+		"audit events left are dropped": {
+			hangs:       true,
+			wantDropped: 3,
+		},
+		"audit events left are dropped failing closed": {
+			failClosed:  true,
+			hangs:       true,
+			wantDropped: 3,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			audit := newFlakySink()
+			defer audit.Close()
+			if tc.hangs {
+				release := make(chan struct{})
+				defer close(release)
+				audit.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					select {
+					case <-release:
+					case <-r.Context().Done():
+					}
+				})
+			}
+
+			au := newAuditor(newTestClient(t, audit.URL), audit.URL, tc.failClosed, zap.NewNop().Sugar())
+			for _, id := range []string{"1", "2", "3"} {
+				event := cloudevents.NewEvent()
+				event.SetID(id)
+				event.SetType(eventType)
+				event.SetSource(eventSource)
+				au.audit(event, auditData{ID: id, Outcome: outcomeDelivered})
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			go au.run(ctx)
+
+			au.flush(100 * time.Millisecond)
+			cancel()
+			<-au.stopped
+
+			assert.Len(t, audit.received(), tc.wantReceived)
+			assert.Equal(t, tc.wantDropped, atomic.LoadInt64(&au.dropped))
+			assert.Zero(t, atomic.LoadInt64(&au.pending))
+			assert.Empty(t, au.queue)
+		})
+	}
+}
+
+func TestAuditFailurePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		policy string
+		// wantSink is received by the sink while the audit sink is down.
+		wantSink  []int
+		wantReady int
+	}{
+		"open keeps sending events": {
+			policy:    failurePolicyOpen,
+			wantSink:  []int{0, 1},
+			wantReady: http.StatusOK,
+		},
+		"closed withholds events": {
+			policy:    failurePolicyClosed,
+			wantSink:  []int{0},
+			wantReady: http.StatusServiceUnavailable,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := newFlakySink()
+			defer sink.Close()
+			audit := newFlakySink()
+			defer audit.Close()
+			audit.setReady(false)
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+				Interval:          time.Millisecond,
+				FailurePolicy:     tc.policy,
+				SinkFailurePolicy: policyFailFast,
+				SinkBufferSize:    10,
+				AuditSink:         audit.URL,
+			}, newTestClient(t, sink.URL)).(*Adapter)
+			a.auditor.retryInterval = time.Millisecond
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go a.auditor.run(ctx)
+			atomic.StoreInt32(&a.started, 1)
+
+			// The audit of event 0 fails.
+			a.tick(ctx)
+			require.Eventually(t, func() bool {
+				return a.auditor.isDegraded() || atomic.LoadInt64(&a.auditor.dropped) == 1
+			}, 5*time.Second, time.Millisecond)
+			a.tick(ctx)
+			assert.Equal(t, tc.wantSink, sink.received())
+			assert.Equal(t, tc.wantReady, ready(a))
+
+			// Withheld events are sent once auditing recovers.
+			audit.setReady(true)
+			require.Eventually(t, func() bool {
+				return !a.auditor.isDegraded()
+			}, 5*time.Second, time.Millisecond)
+			a.tick(ctx)
+			assert.Equal(t, []int{0, 1, 2}, sink.received())
+			assert.Equal(t, http.StatusOK, ready(a))
+		})
+	}
+}
+
+// ready returns the status code of the adapter readiness endpoint.
+func ready(a *Adapter) int {
+	w := httptest.NewRecorder()
+	a.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w.Code
+}
+
+func TestAuditFailClosedRequiresBuffer(t *testing.T) {
+	// Use the test executable to run an adapter withholding events without
+	// a buffer if environment var t.Name() is set to "main".
+	if os.Getenv(t.Name()) == "main" {
+		ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+		NewAdapter(ctx, &envConfig{
+			EnvConfig:         adapter.EnvConfig{Sink: "http://127.0.0.1:1"},
+			Interval:          time.Millisecond,
+			FailurePolicy:     failurePolicyClosed,
+			SinkFailurePolicy: policyFailFast,
+			SinkBufferSize:    0,
+			AuditSink:         "http://127.0.0.1:1",
+		}, newTestClient(t, "http://127.0.0.1:1"))
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+t.Name()+"$")
+	cmd.Env = append(os.Environ(), t.Name()+"=main")
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr), "adapter must not start without a buffer, got %v", err)
+}
+
+// TestAdapterShutdownFailingClosed shuts down an adapter withholding events
+// while the audit sink fails, and checks that shutdown is bounded although
+// the audit queue is full.
+func TestAdapterShutdownFailingClosed(t *testing.T) {
+	sink := newFlakySink()
+	defer sink.Close()
+	audit := newFlakySink()
+	defer audit.Close()
+	audit.setReady(false)
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	a := NewAdapter(ctx, &envConfig{
+		EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+		Interval:          time.Millisecond,
+		FailurePolicy:     failurePolicyClosed,
+		SinkFailurePolicy: policyFailFast,
+		SinkBufferSize:    100,
+		SinkDrainTimeout:  10 * time.Millisecond,
+		AuditSink:         audit.URL,
+	}, newTestClient(t, sink.URL)).(*Adapter)
+	a.auditor.queue = make(chan cloudevents.Event, 1)
+	a.auditor.retryInterval = time.Millisecond
+	a.auditor.flushTimeout = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		a.Start(ctx)
+		close(done)
+	}()
+	// Once the buffer is full, the event evicted waits for room in the audit
+	// queue.
+	require.Eventually(t, func() bool { return a.slo.count(outcomeLost) > 0 }, 5*time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown is not bounded")
+	}
+
+	// The events sent before the auditor degraded are delivered, the withheld
+	// ones are lost, and none of the audit events reached the audit sink.
+	assert.Equal(t, int64(len(sink.received())), a.slo.count(outcomeDelivered))
+	assert.Equal(t, a.slo.count(outcomeDelivered)+a.slo.count(outcomeLost), atomic.LoadInt64(&a.auditor.dropped))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"go.opencensus.io/plugin/ochttp"
+
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/eventing/pkg/adapter/v2/util/crstatusevent"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/source"
+	"knative.dev/pkg/tracing/propagation/tracecontextb3"
+)
+
+// newClient returns a CloudEvents client delivering to the sink through rt.
+// It replaces the client provided by the adapter framework when outbound
+// requests have to be modified on the wire, and applies the CloudEvents
+// overrides, the sink timeout and the event count reporting the same way the
+// framework client does.
+func newClient(env *envConfig, rt http.RoundTripper, reporter source.StatsReporter, crStatusEventClient *crstatusevent.CRStatusEventClient) (cloudevents.Client, error) {
+	// The client is set explicitly: the protocol would otherwise install rt as
+	// the transport of http.DefaultClient, shared with every other client.
+	hc := http.Client{Transport: &ochttp.Transport{
+		Base:        rt,
+		Propagation: tracecontextb3.TraceContextEgress,
+	}}
+	if sinkWait := env.GetSinktimeout(); sinkWait > 0 {
+		hc.Timeout = time.Duration(sinkWait) * time.Second
+	}
+	p, err := cloudevents.NewHTTP(cloudevents.WithTarget(env.Sink), cehttp.WithClient(hc))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := cloudevents.NewClientObserved(p, cloudevents.WithTimeNow(), cloudevents.WithUUIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	ceOverrides, err := env.GetCloudEventOverrides()
+	if err != nil {
+		return nil, err
+	}
+	if crStatusEventClient == nil {
+		crStatusEventClient = crstatusevent.GetDefaultClient()
+	}
+	return &client{
+		Client:              c,
+		ceOverrides:         ceOverrides,
+		reporter:            reporter,
+		crStatusEventClient: crStatusEventClient,
+	}, nil
+}
+
+// newCRStatusEventClient returns the client reporting failed deliveries as
+// Kubernetes events, configured from the metrics configuration like the one
+// of the adapter framework.
+func newCRStatusEventClient(env *envConfig) *crstatusevent.CRStatusEventClient {
+	if cfg, err := env.GetMetricsConfig(); err == nil && cfg != nil && cfg.ConfigMap != nil {
+		return crstatusevent.NewCRStatusEventClient(cfg.ConfigMap)
+	}
+	return crstatusevent.GetDefaultClient()
+}
+
+// client applies CloudEvents overrides to events before sending them, and
+// reports the outcome of each delivery.
+type client struct {
+	cloudevents.Client
+	ceOverrides         *duckv1.CloudEventOverrides
+	reporter            source.StatsReporter
+	crStatusEventClient *crstatusevent.CRStatusEventClient
+}
+
+// Send implements cloudevents.Client.
+func (c *client) Send(ctx context.Context, out cloudevents.Event) protocol.Result {
+	c.applyOverrides(&out)
+	res := c.Client.Send(ctx, out)
+	return c.reportCount(ctx, out, res)
+}
+
+// Request implements cloudevents.Client.
+func (c *client) Request(ctx context.Context, out cloudevents.Event) (*cloudevents.Event, protocol.Result) {
+	c.applyOverrides(&out)
+	resp, res := c.Client.Request(ctx, out)
+	return resp, c.reportCount(ctx, out, res)
+}
+
+func (c *client) applyOverrides(event *cloudevents.Event) {
+	if c.ceOverrides != nil {
+		for n, v := range c.ceOverrides.Extensions {
+			event.SetExtension(n, v)
+		}
+	}
+}
+
+// reportCount reports the event count of the delivery of event, like the
+// framework client. Failed deliveries are also reported as Kubernetes events.
+func (c *client) reportCount(ctx context.Context, event cloudevents.Event, result protocol.Result) protocol.Result {
+	tags := adapter.MetricTagFromContext(ctx)
+	reportArgs := &source.ReportArgs{
+		Namespace:     tags.Namespace,
+		EventSource:   event.Source(),
+		EventType:     event.Type(),
+		Name:          tags.Name,
+		ResourceGroup: tags.ResourceGroup,
+	}
+
+	var rres *cehttp.RetriesResult
+	if cloudevents.ResultAs(result, &rres) {
+		result = rres.Result
+	}
+	if !cloudevents.IsACK(result) {
+		c.crStatusEventClient.ReportCRStatusEvent(ctx, result)
+	}
+
+	statusCode := 0
+	var res *cehttp.Result
+	if cloudevents.ResultAs(result, &res) {
+		statusCode = res.StatusCode
+	} else {
+		var uErr *url.Error
+		if errors.As(result, &uErr) {
+			reportArgs.Timeout = uErr.Timeout()
+		}
+		if result != nil {
+			reportArgs.Error = result.Error()
+		}
+	}
+	if c.reporter == nil {
+		return result
+	}
+	if err := c.reporter.ReportEventCount(reportArgs, statusCode); err != nil && result != nil {
+		// Metrics are not important enough to fail the delivery.
+		result = fmt.Errorf("%w\nmetrics reporter error: %s", result, err)
+	}
+	return result
+}
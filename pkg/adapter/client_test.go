@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/source"
+)
+
+// reportedCount is an event count reported by a fakeReporter.
+type reportedCount struct {
+	args source.ReportArgs
+	code int
+}
+
+// fakeReporter records the event counts reported to it.
+type fakeReporter struct {
+	mu     sync.Mutex
+	counts []reportedCount
+}
+
+func (r *fakeReporter) ReportEventCount(args *source.ReportArgs, responseCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts = append(r.counts, reportedCount{args: *args, code: responseCode})
+	return nil
+}
+
+func (r *fakeReporter) ReportRetryEventCount(*source.ReportArgs, int) error {
+	return nil
+}
+
+func TestClientReportsEventCount(t *testing.T) {
+	testCases := map[string]struct {
+		status   int
+		down     bool
+		wantCode int
+		wantErr  bool
+	}{
+		"accepted": {
+			status:   http.StatusAccepted,
+			wantCode: http.StatusAccepted,
+		},
+		"rejected": {
+			status:   http.StatusInternalServerError,
+			wantCode: http.StatusInternalServerError,
+		},
+		"unreachable": {
+			down:     true,
+			wantCode: 0,
+			wantErr:  true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "a", r.Header.Get("Ce-Team"), "overrides must be applied")
+				w.WriteHeader(tc.status)
+			}))
+			if tc.down {
+				srv.Close()
+			} else {
+				defer srv.Close()
+			}
+
+			reporter := &fakeReporter{}
+			c, err := newClient(&envConfig{EnvConfig: adapter.EnvConfig{
+				Sink:        srv.URL,
+				CEOverrides: `{"extensions":{"team":"a"}}`,
+			}}, http.DefaultTransport, reporter, nil)
+			require.NoError(t, err)
+
+			ctx := adapter.ContextWithMetricTag(context.Background(), &adapter.MetricTag{
+				Name:          "source",
+				Namespace:     "ns",
+				ResourceGroup: "samplesources.samples.knative.dev",
+			})
+			event := cloudevents.NewEvent()
+			event.SetType("dev.knative.sample")
+			event.SetSource("sample")
+			event.SetID("1")
+			c.Send(ctx, event)
+
+			require.Len(t, reporter.counts, 1)
+			got := reporter.counts[0]
+			assert.Equal(t, tc.wantCode, got.code)
+			assert.Equal(t, "ns", got.args.Namespace)
+			assert.Equal(t, "source", got.args.Name)
+			assert.Equal(t, "samplesources.samples.knative.dev", got.args.ResourceGroup)
+			assert.Equal(t, "dev.knative.sample", got.args.EventType)
+			assert.Equal(t, "sample", got.args.EventSource)
+			assert.Equal(t, tc.wantErr, got.args.Error != "", "error = %q", got.args.Error)
+		})
+	}
+
+	assert.Nil(t, http.DefaultClient.Transport, "the shared client must not be modified")
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// compressionTransport is an http.RoundTripper which gzips the body of
+// outbound requests larger than threshold, once the sink supports it. A sink
+// advertises support with the Accept-Encoding header of a response, as in
+// RFC 7694.
+type compressionTransport struct {
+	base      http.RoundTripper
+	threshold int
+	// assumed is set if the sink is assumed to support gzip regardless of
+	// what it advertises.
+	assumed bool
+	// supported is non-zero once the sink has advertised gzip support.
+	supported int32
+}
+
+var _ http.RoundTripper = (*compressionTransport)(nil)
+
+func newCompressionTransport(base http.RoundTripper, threshold int, assumeSupported bool) *compressionTransport {
+	return &compressionTransport{
+		base:      base,
+		threshold: threshold,
+		assumed:   assumeSupported,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A RoundTripper must not modify the request it was given.
+	out := req.Clone(req.Context())
+	compressed := len(body) > t.threshold && t.isSupported()
+	if compressed {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+		out.Header.Set("Content-Encoding", "gzip")
+	}
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.ContentLength = int64(len(body))
+
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case acceptsGzip(resp.Header):
+		atomic.StoreInt32(&t.supported, 1)
+	case compressed && resp.StatusCode == http.StatusUnsupportedMediaType:
+		// The sink no longer accepts compressed bodies.
+		atomic.StoreInt32(&t.supported, 0)
+	}
+	return resp, nil
+}
+
+func (t *compressionTransport) isSupported() bool {
+	return t.assumed || atomic.LoadInt32(&t.supported) != 0
+}
+
+// acceptsGzip returns whether the Accept-Encoding header in h lists gzip
+// with a non-zero quality value.
+func acceptsGzip(h http.Header) bool {
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(v, ",") {
+			params := strings.Split(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "gzip") {
+				continue
+			}
+			q := 1.0
+			for _, p := range params[1:] {
+				if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+					q, _ = strconv.ParseFloat(p[2:], 64)
+				}
+			}
+			if q > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGzipSink returns a test sink recording the raw requests it receives,
+// which advertises gzip support in its responses if advertise is set.
+func newGzipSink(advertise bool) (*httptest.Server, chan request) {
+	received := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- request{header: r.Header, body: body}
+		if advertise {
+			w.Header().Set("Accept-Encoding", "gzip")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return srv, received
+}
+
+func post(t *testing.T, c *http.Client, url, body string) {
+	t.Helper()
+	resp, err := c.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestCompressionTransport(t *testing.T) {
+	large := `{"sequence":1,"heartbeat":"` + strings.Repeat("1", 64) + `"}`
+	small := `{"sequence":1}`
+
+	t.Run("compresses large bodies if assumed supported", func(t *testing.T) {
+		srv, received := newGzipSink(false)
+		defer srv.Close()
+		c := &http.Client{Transport: newCompressionTransport(http.DefaultTransport, 32, true)}
+
+		post(t, c, srv.URL, large)
+		r := <-received
+		assert.Equal(t, "gzip", r.header.Get("Content-Encoding"))
+		assert.Equal(t, large, gunzip(t, r.body))
+
+		post(t, c, srv.URL, small)
+		r = <-received
+		assert.Empty(t, r.header.Get("Content-Encoding"))
+		assert.Equal(t, small, string(r.body))
+	})
+
+	t.Run("compresses once the sink advertises support", func(t *testing.T) {
+		srv, received := newGzipSink(true)
+		defer srv.Close()
+		c := &http.Client{Transport: newCompressionTransport(http.DefaultTransport, 32, false)}
+
+		post(t, c, srv.URL, large)
+		r := <-received
+		assert.Empty(t, r.header.Get("Content-Encoding"))
+		assert.Equal(t, large, string(r.body))
+
+		post(t, c, srv.URL, large)
+		r = <-received
+		assert.Equal(t, "gzip", r.header.Get("Content-Encoding"))
+		assert.Equal(t, large, gunzip(t, r.body))
+	})
+
+	t.Run("does not compress if the sink does not advertise support", func(t *testing.T) {
+		srv, received := newGzipSink(false)
+		defer srv.Close()
+		c := &http.Client{Transport: newCompressionTransport(http.DefaultTransport, 32, false)}
+
+		for i := 0; i < 2; i++ {
+			post(t, c, srv.URL, large)
+			r := <-received
+			assert.Empty(t, r.header.Get("Content-Encoding"))
+			assert.Equal(t, large, string(r.body))
+		}
+	})
+
+	t.Run("signs the compressed body", func(t *testing.T) {
+		srv, received := newGzipSink(false)
+		defer srv.Close()
+		c := &http.Client{Transport: newCompressionTransport(&signingTransport{
+			base:   http.DefaultTransport,
+			secret: []byte("s3cr3t"),
+			header: "X-Signature-256",
+		}, 32, true)}
+
+		for _, body := range []string{large, small} {
+			post(t, c, srv.URL, body)
+			r := <-received
+			assert.Equal(t, Sign([]byte("s3cr3t"), r.body), r.header.Get("X-Signature-256"))
+		}
+	})
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for v, want := range map[string]bool{
+		"":                  false,
+		"identity":          false,
+		"gzip":              true,
+		"GZIP":              true,
+		"br, gzip;q=0.5":    true,
+		"gzip;q=0":          false,
+		"gzip; q=0.000":     false,
+		"deflate, x-gzip":   false,
+		"identity, gzip ; ": true,
+	} {
+		h := http.Header{}
+		if v != "" {
+			h.Set("Accept-Encoding", v)
+		}
+		assert.Equal(t, want, acceptsGzip(h), "Accept-Encoding: %q", v)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Policies for events which could not be delivered to the sink.
+const (
+	// policyFailFast drops the event.
+	policyFailFast = "FailFast"
+	// policyBuffer keeps the event in a bounded buffer and retries it
+	// before sending the next event.
+	policyBuffer = "Buffer"
+	// policyFallback sends the event to a static fallback URI instead.
+	policyFallback = "Fallback"
+)
+
+// drainFallbackTimeout bounds how long the events left over by the drain are
+// sent to the fallback URI on shutdown.
+const drainFallbackTimeout = 5 * time.Second
+
+// Policies for failures of signing and auditing.
+const (
+	// failurePolicyOpen keeps sending events, unsigned or unaudited.
+	failurePolicyOpen = "Open"
+	// failurePolicyClosed withholds events while the signing secret cannot
+	// be read or auditing fails.
+	failurePolicyClosed = "Closed"
+)
+
+// Policies for events which are not valid CloudEvents.
+const (
+	// invalidEventDrop drops the event.
+	invalidEventDrop = "Drop"
+	// invalidEventFallback sends an event reporting the invalid event to the
+	// fallback URI.
+	invalidEventFallback = "Fallback"
+)
+
+// invalidEventType is the type of the events reporting invalid events.
+const invalidEventType = "dev.knative.sample.invalid"
+
+// invalidEventData is the data of the events reporting invalid events.
+type invalidEventData struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// bufferedEvent is an event the sink did not acknowledge yet.
+type bufferedEvent struct {
+	event cloudevents.Event
+	// attempts is the number of times the event was sent.
+	attempts int
+	// reason is why the last attempt failed.
+	reason string
+}
+
+// send delivers event to the sink, applying the sink failure policy if the
+// sink does not acknowledge it. Invalid events are not sent to the sink.
+func (a *Adapter) send(ctx context.Context, event cloudevents.Event) {
+	if err := event.Validate(); err != nil {
+		a.reject(ctx, event, err)
+		return
+	}
+
+	if dependency := a.degradedDependency(); dependency != "" {
+		a.logger.Infow("Withholding event while a dependency fails", zap.String("id", event.ID()),
+			zap.String("dependency", dependency))
+		a.bufferEvent(bufferedEvent{event: event})
+		return
+	}
+	if len(a.buffer) > 0 {
+		// The sink did not acknowledge the buffered events yet. They are
+		// delivered first, in order.
+		a.bufferEvent(bufferedEvent{event: event})
+		return
+	}
+
+	a.logger.Infow("Sending new event", zap.String("event", event.String()))
+	result := a.client.Send(ctx, event)
+	a.record(event, result, a.sink)
+	if cloudevents.IsACK(result) {
+		a.finish(event, outcomeDelivered, a.sink, "", 1)
+		return
+	}
+	a.logger.Infow("failed to send event", zap.String("event", event.String()), zap.Error(result))
+	b := bufferedEvent{event: event, attempts: 1, reason: failureReason(result)}
+
+	switch a.sinkFailurePolicy {
+	case policyBuffer:
+		a.bufferEvent(b)
+
+	case policyFallback:
+		if !a.deadLetter(ctx, &b) {
+			a.finish(event, outcomeDropped, a.fallbackURI, b.reason, b.attempts)
+		}
+
+	default:
+		// We got an error but it could be transient, the next event is sent next interval.
+		a.finish(event, outcomeDropped, a.sink, b.reason, b.attempts)
+	}
+}
+
+// deadLetter sends the event of b to the fallback URI, and returns whether
+// the fallback URI acknowledged it. It accounts for the event as
+// deadlettered if so.
+func (a *Adapter) deadLetter(ctx context.Context, b *bufferedEvent) bool {
+	b.attempts++
+	result := a.client.Send(cecontext.WithTarget(ctx, a.fallbackURI), b.event)
+	a.record(b.event, result, a.fallbackURI)
+	if !cloudevents.IsACK(result) {
+		a.logger.Infow("failed to send event to fallback", zap.String("event", b.event.String()), zap.Error(result))
+		b.reason = failureReason(result)
+		return false
+	}
+	a.finish(b.event, outcomeDeadlettered, a.fallbackURI, b.reason, b.attempts)
+	return true
+}
+
+// bufferEvent appends b to the buffer, dropping the oldest event if it is
+// full.
+func (a *Adapter) bufferEvent(b bufferedEvent) {
+	if len(a.buffer) == a.bufferSize {
+		oldest := a.buffer[0]
+		a.logger.Warnw("Buffer full, dropping oldest event", zap.String("id", oldest.event.ID()))
+		a.finish(oldest.event, outcomeLost, a.sink, "buffer full", oldest.attempts)
+		a.buffer = a.buffer[1:]
+	}
+	a.buffer = append(a.buffer, b)
+}
+
+// flush retries the buffered events in order, stopping at the first one the
+// sink still does not acknowledge.
+func (a *Adapter) flush(ctx context.Context) {
+	for len(a.buffer) > 0 && !a.degraded() {
+		b := &a.buffer[0]
+		b.attempts++
+		result := a.client.Send(ctx, b.event)
+		a.record(b.event, result, a.sink)
+		if !cloudevents.IsACK(result) {
+			a.logger.Infow("failed to resend buffered event", zap.String("id", b.event.ID()), zap.Error(result))
+			b.reason = failureReason(result)
+			return
+		}
+		a.finish(b.event, outcomeDelivered, a.sink, "", b.attempts)
+		a.buffer = a.buffer[1:]
+	}
+}
+
+// drain retries the buffered events until the sink has acknowledged all of
+// them or the drain timeout expires. The adapter keeps delivering to the sink
+// it was started with, so that events buffered for a sink being replaced
+// still reach it. The events left are then sent to the fallback URI, if set,
+// and the ones it does not acknowledge are accounted for as lost.
+func (a *Adapter) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.drainTimeout)
+	defer cancel()
+	for len(a.buffer) > 0 && ctx.Err() == nil {
+		a.flush(ctx)
+		if len(a.buffer) > 0 {
+			select {
+			case <-time.After(a.interval):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	if len(a.buffer) > 0 && a.fallbackURI != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), drainFallbackTimeout)
+		defer cancel()
+		a.logger.Infow("Sending buffered events to fallback on shutdown", zap.Int("events", len(a.buffer)))
+		left := a.buffer[:0]
+		for _, b := range a.buffer {
+			if !a.deadLetter(ctx, &b) {
+				left = append(left, b)
+			}
+		}
+		a.buffer = left
+	}
+
+	for _, b := range a.buffer {
+		sink, reason := a.sink, "drain timeout"
+		if a.fallbackURI != "" {
+			sink, reason = a.fallbackURI, b.reason
+		}
+		a.finish(b.event, outcomeLost, sink, reason, b.attempts)
+	}
+	if len(a.buffer) > 0 {
+		a.logger.Warnw("Lost buffered events on shutdown", zap.Int("lost", len(a.buffer)))
+	}
+	a.buffer = nil
+}
+
+// reject applies the invalid event policy to event, which failed validation
+// with err.
+func (a *Adapter) reject(ctx context.Context, event cloudevents.Event, err error) {
+	a.logger.Warnw("Not sending invalid event", zap.String("id", event.ID()), zap.Error(err))
+	a.recordStatus(event, statusInvalid, "", err.Error())
+	a.finish(event, outcomeDropped, "", err.Error(), 0)
+	if a.invalidEventPolicy != invalidEventFallback {
+		return
+	}
+
+	report := cloudevents.NewEvent()
+	report.SetID(uuid.New().String())
+	report.SetType(invalidEventType)
+	report.SetSource(eventSource)
+	if err := report.SetData(cloudevents.ApplicationJSON, &invalidEventData{
+		ID:     event.ID(),
+		Source: event.Source(),
+		Type:   event.Type(),
+		Reason: err.Error(),
+	}); err != nil {
+		a.logger.Errorw("failed to set data")
+		return
+	}
+	if result := a.client.Send(cecontext.WithTarget(ctx, a.fallbackURI), report); !cloudevents.IsACK(result) {
+		a.logger.Infow("failed to report invalid event to fallback", zap.String("id", event.ID()), zap.Error(result))
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+// flakySink is a test sink which can be made unready, in which case it
+// rejects events like an unready Broker would.
+type flakySink struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	unready   bool
+	sequences []int
+}
+
+func newFlakySink() *flakySink {
+	s := &flakySink{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.unready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		d := &dataExample{}
+		if err := json.NewDecoder(r.Body).Decode(d); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.sequences = append(s.sequences, d.Sequence)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return s
+}
+
+func (s *flakySink) setReady(ready bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unready = !ready
+}
+
+func (s *flakySink) received() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int{}, s.sequences...)
+}
+
+func TestSinkFailurePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		policy       string
+		bufferSize   int
+		wantSink     []int
+		wantFallback []int
+		wantOutcomes map[string]int64
+	}{
+		"fail fast drops events while unready": {
+			policy:       policyFailFast,
+			wantSink:     []int{0, 4},
+			wantOutcomes: map[string]int64{outcomeDelivered: 2, outcomeDropped: 3},
+		},
+		"buffer redelivers events once ready": {
+			policy:       policyBuffer,
+			bufferSize:   10,
+			wantSink:     []int{0, 1, 2, 3, 4},
+			wantOutcomes: map[string]int64{outcomeDelivered: 5},
+		},
+		"buffer drops the oldest events when full": {
+			policy:       policyBuffer,
+			bufferSize:   2,
+			wantSink:     []int{0, 2, 3, 4},
+			wantOutcomes: map[string]int64{outcomeDelivered: 4, outcomeLost: 1},
+		},
+		"fallback receives events while unready": {
+			policy:       policyFallback,
+			wantSink:     []int{0, 4},
+			wantFallback: []int{1, 2, 3},
+			wantOutcomes: map[string]int64{outcomeDelivered: 2, outcomeDeadlettered: 3},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := newFlakySink()
+			defer sink.Close()
+			fallback := newFlakySink()
+			defer fallback.Close()
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+				Interval:          time.Millisecond,
+				SinkFailurePolicy: tc.policy,
+				SinkBufferSize:    tc.bufferSize,
+				SinkFallbackURI:   fallback.URL,
+			}, newTestClient(t, sink.URL)).(*Adapter)
+
+			// Event 0 is delivered, 1 to 3 are sent while the sink is
+			// unready, and 4 once it has recovered.
+			a.tick(ctx)
+			sink.setReady(false)
+			for i := 0; i < 3; i++ {
+				a.tick(ctx)
+			}
+			sink.setReady(true)
+			a.tick(ctx)
+
+			assert.Equal(t, tc.wantSink, sink.received(), "sink")
+			assert.Equal(t, append([]int{}, tc.wantFallback...), fallback.received(), "fallback")
+			assert.Empty(t, a.buffer)
+			for _, outcome := range []string{outcomeDelivered, outcomeDeadlettered, outcomeDropped, outcomeLost} {
+				assert.Equal(t, tc.wantOutcomes[outcome], a.slo.count(outcome), outcome)
+			}
+		})
+	}
+}
+
+func TestInvalidEvents(t *testing.T) {
+	invalidSource := cloudevents.NewEvent()
+	invalidSource.SetID("invalid-source")
+	invalidSource.SetType(eventType)
+
+	invalidType := cloudevents.NewEvent()
+	invalidType.SetID("invalid-type")
+	invalidType.SetSource(eventSource)
+	invalidType.SetType(" ")
+
+	for _, policy := range []string{invalidEventDrop, invalidEventFallback} {
+		for _, event := range []cloudevents.Event{invalidSource, invalidType} {
+			t.Run(policy+" "+event.ID(), func(t *testing.T) {
+				sink := newFlakySink()
+				defer sink.Close()
+				fallback := newSink(t)
+				defer fallback.close()
+
+				ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+				a := NewAdapter(ctx, &envConfig{
+					EnvConfig:          adapter.EnvConfig{Sink: sink.URL},
+					Interval:           time.Millisecond,
+					InvalidEventPolicy: policy,
+					SinkFailurePolicy:  policyFailFast,
+					SinkFallbackURI:    fallback.URL(),
+					RecentEventsSize:   10,
+					RecentEventsMaxAge: time.Hour,
+				}, newTestClient(t, sink.URL)).(*Adapter)
+
+				if policy == invalidEventFallback {
+					// The fallback sink blocks until the report is read.
+					go a.send(ctx, event)
+					report, ok := <-fallback.received
+					require.True(t, ok, "timed out waiting for the report")
+					assert.Equal(t, invalidEventType, report.Type())
+					d := &invalidEventData{}
+					require.NoError(t, report.DataAs(d))
+					assert.Equal(t, event.ID(), d.ID)
+					assert.NotEmpty(t, d.Reason)
+				} else {
+					a.send(ctx, event)
+				}
+
+				assert.Empty(t, sink.received(), "invalid events must not be sent to the sink")
+				// The event is recorded before it is reported.
+				recs := a.recent.lookup(event.ID())
+				require.Len(t, recs, 1)
+				assert.Equal(t, statusInvalid, recs[0].Status)
+				assert.NotEmpty(t, recs[0].Reason)
+				assert.Equal(t, int64(1), a.slo.count(outcomeDropped))
+			})
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	testCases := map[string]struct {
+		// recovers makes the sink ready again before the adapter shuts down.
+		recovers bool
+		wantSink []int
+		wantLost int64
+	}{
+		"buffered events reach the sink": {
+			recovers: true,
+			wantSink: []int{0, 1, 2},
+		},
+		"buffered events are lost after the timeout": {
+			wantLost: 3,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := newFlakySink()
+			defer sink.Close()
+			sink.setReady(false)
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+				Interval:          time.Millisecond,
+				SinkFailurePolicy: policyBuffer,
+				SinkBufferSize:    10,
+				SinkDrainTimeout:  100 * time.Millisecond,
+			}, newTestClient(t, sink.URL)).(*Adapter)
+
+			for i := 0; i < 3; i++ {
+				a.tick(ctx)
+			}
+			sink.setReady(tc.recovers)
+			start := time.Now()
+			a.drain()
+
+			assert.Less(t, int64(time.Since(start)), int64(5*time.Second), "drain must be bounded")
+			assert.Equal(t, append([]int{}, tc.wantSink...), sink.received())
+			assert.Equal(t, tc.wantLost, a.slo.count(outcomeLost))
+			assert.Empty(t, a.buffer)
+		})
+	}
+}
+
+// TestAdapterShutdownDrainsToOldSink replaces an adapter buffering events for
+// an unready sink with one sending to a new sink, and checks that the
+// buffered events still reach the old sink once it recovers.
+func TestAdapterShutdownDrainsToOldSink(t *testing.T) {
+	oldSink := newFlakySink()
+	defer oldSink.Close()
+	oldSink.setReady(false)
+	newSink := newFlakySink()
+	defer newSink.Close()
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	start := func(sink string) (*Adapter, context.CancelFunc, chan struct{}) {
+		a := NewAdapter(ctx, &envConfig{
+			EnvConfig:         adapter.EnvConfig{Sink: sink},
+			Interval:          time.Millisecond,
+			SinkFailurePolicy: policyBuffer,
+			SinkBufferSize:    1000,
+			SinkDrainTimeout:  5 * time.Second,
+		}, newTestClient(t, sink)).(*Adapter)
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			a.Start(ctx)
+			close(done)
+		}()
+		return a, cancel, done
+	}
+
+	oldAdapter, stopOld, oldDone := start(oldSink.URL)
+	time.Sleep(20 * time.Millisecond)
+	newAdapter, stopNew, newDone := start(newSink.URL)
+	stopOld()
+	oldSink.setReady(true)
+	<-oldDone
+	stopNew()
+	<-newDone
+
+	sequences := func(n int) []int {
+		s := make([]int, n)
+		for i := range s {
+			s[i] = i
+		}
+		return s
+	}
+	assert.NotZero(t, oldAdapter.nextID)
+	assert.Equal(t, sequences(oldAdapter.nextID), oldSink.received(), "the old sink must receive all the buffered events")
+	assert.Equal(t, sequences(newAdapter.nextID), newSink.received(), "the new sink must only receive the events of the new adapter")
+	assert.Zero(t, oldAdapter.slo.count(outcomeLost))
+	assert.Equal(t, int64(oldAdapter.nextID), oldAdapter.slo.count(outcomeDelivered))
+}
+
+// TestAdapterShutdownDeadLettersLeftovers shuts down an adapter buffering
+// events for an unready sink, and checks that the events left over by the
+// drain go to the fallback URI, each exactly once.
+func TestAdapterShutdownDeadLettersLeftovers(t *testing.T) {
+	testCases := map[string]struct {
+		fallbackDown     bool
+		wantDeadLettered bool
+	}{
+		"leftovers are dead-lettered": {
+			wantDeadLettered: true,
+		},
+		"leftovers the fallback rejects are lost": {
+			fallbackDown: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			sink := newFlakySink()
+			defer sink.Close()
+			sink.setReady(false)
+			fallback := newFlakySink()
+			defer fallback.Close()
+			fallback.setReady(!tc.fallbackDown)
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+				Interval:          time.Millisecond,
+				SinkFailurePolicy: policyBuffer,
+				SinkBufferSize:    1000,
+				SinkFallbackURI:   fallback.URL,
+				SinkDrainTimeout:  50 * time.Millisecond,
+			}, newTestClient(t, sink.URL)).(*Adapter)
+			ctx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				a.Start(ctx)
+				close(done)
+			}()
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+			<-done
+
+			n := a.nextID
+			require.NotZero(t, n)
+			assert.Empty(t, sink.received())
+			assert.Empty(t, a.buffer)
+			if tc.wantDeadLettered {
+				want := make([]int, n)
+				for i := range want {
+					want[i] = i
+				}
+				assert.Equal(t, want, fallback.received(), "every event must be dead-lettered exactly once")
+				assert.Equal(t, int64(n), a.slo.count(outcomeDeadlettered))
+				assert.Zero(t, a.slo.count(outcomeLost))
+			} else {
+				assert.Empty(t, fallback.received())
+				assert.Zero(t, a.slo.count(outcomeDeadlettered))
+				assert.Equal(t, int64(n), a.slo.count(outcomeLost))
+			}
+		})
+	}
+}
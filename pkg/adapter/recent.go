@@ -0,0 +1,114 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// statusDelivered is recorded for events acknowledged by the sink.
+	statusDelivered = "delivered"
+	// statusFailed is recorded for events which could not be delivered.
+	statusFailed = "failed"
+	// statusInvalid is recorded for events which are not valid CloudEvents,
+	// and were not sent.
+	statusInvalid = "invalid"
+)
+
+// eventRecord describes the outcome of sending an event.
+type eventRecord struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Sink   string    `json:"sink"`
+	// Reason is why the event failed or is invalid.
+	Reason string `json:"reason,omitempty"`
+}
+
+// recentEvents is a ring buffer of the records of recently sent events,
+// bounded both by count and by age.
+type recentEvents struct {
+	mu      sync.Mutex
+	records []eventRecord
+	// next is the index the next record is written to.
+	next int
+	// full is set once the buffer has wrapped around.
+	full bool
+
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+func newRecentEvents(size int, maxAge time.Duration) *recentEvents {
+	return &recentEvents{
+		records: make([]eventRecord, size),
+		maxAge:  maxAge,
+		now:     time.Now,
+	}
+}
+
+// add records an event, evicting the oldest record when the buffer is full.
+func (r *recentEvents) add(rec eventRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// lookup returns the unexpired records whose id starts with prefix, newest
+// first. An empty prefix matches every record.
+func (r *recentEvents) lookup(prefix string) []eventRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.records)
+	}
+	cutoff := r.now().Add(-r.maxAge)
+
+	found := []eventRecord{}
+	for i := 1; i <= n; i++ {
+		rec := r.records[(r.next-i+len(r.records))%len(r.records)]
+		if rec.Time.Before(cutoff) {
+			// Records are stored in insertion order, so the rest are older.
+			break
+		}
+		if strings.HasPrefix(rec.ID, prefix) {
+			found = append(found, rec)
+		}
+	}
+	return found
+}
+
+// ServeHTTP serves the records matching the "id" query parameter as JSON.
+func (r *recentEvents) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.lookup(req.URL.Query().Get("id")))
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+func ids(records []eventRecord) []string {
+	out := make([]string, 0, len(records))
+	for _, r := range records {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func TestRecentEvents(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	r := newRecentEvents(3, time.Hour)
+	r.now = func() time.Time { return now }
+
+	assert.Empty(t, r.lookup(""))
+
+	r.add(eventRecord{ID: "abc-1", Time: now})
+	r.add(eventRecord{ID: "abd-2", Time: now})
+	assert.Equal(t, []string{"abd-2", "abc-1"}, ids(r.lookup("")), "insertion")
+
+	r.add(eventRecord{ID: "xyz-3", Time: now})
+	r.add(eventRecord{ID: "abc-4", Time: now})
+	assert.Equal(t, []string{"abc-4", "xyz-3", "abd-2"}, ids(r.lookup("")), "wraparound")
+
+	assert.Equal(t, []string{"abc-4", "abd-2"}, ids(r.lookup("ab")), "prefix")
+	assert.Equal(t, []string{"abc-4"}, ids(r.lookup("abc")), "prefix")
+	assert.Empty(t, r.lookup("nope"), "prefix")
+}
+
+func TestRecentEventsMaxAge(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	r := newRecentEvents(10, time.Hour)
+	r.now = func() time.Time { return now }
+
+	r.add(eventRecord{ID: "old", Time: now.Add(-2 * time.Hour)})
+	r.add(eventRecord{ID: "edge", Time: now.Add(-time.Hour)})
+	r.add(eventRecord{ID: "new", Time: now.Add(-time.Minute)})
+
+	assert.Equal(t, []string{"new", "edge"}, ids(r.lookup("")))
+}
+
+func TestRecentEventsHandler(t *testing.T) {
+	r := newRecentEvents(10, time.Hour)
+	r.add(eventRecord{ID: "abc", Time: time.Now(), Status: statusDelivered, Sink: "http://sink"})
+	r.add(eventRecord{ID: "xyz", Time: time.Now(), Status: statusFailed, Sink: "http://sink"})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events?id=ab")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got []eventRecord
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "abc", got[0].ID)
+	assert.Equal(t, statusDelivered, got[0].Status)
+	assert.Equal(t, "http://sink", got[0].Sink)
+
+	resp, err = http.Post(srv.URL+"/events", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestAdapterRecentEvents(t *testing.T) {
+	srv, received := newRawSink(t)
+	defer srv.Close()
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	a := NewAdapter(ctx, &envConfig{
+		EnvConfig:          adapter.EnvConfig{Sink: srv.URL},
+		Interval:           time.Millisecond,
+		RecentEventsSize:   10,
+		RecentEventsMaxAge: time.Hour,
+	}, newTestClient(t, srv.URL)).(*Adapter)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go a.Start(ctx)
+
+	r := <-received
+	id := r.header.Get("Ce-Id")
+	require.NotEmpty(t, id)
+	assert.Eventually(t, func() bool {
+		got := a.recent.lookup(id)
+		return len(got) == 1 && got[0].Status == statusDelivered && got[0].Sink == srv.URL
+	}, 5*time.Second, 10*time.Millisecond)
+}
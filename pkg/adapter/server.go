@@ -0,0 +1,108 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.uber.org/zap"
+	"knative.dev/pkg/metrics"
+)
+
+// handlerPanicsM counts the panics recovered in the adapter HTTP handlers.
+var handlerPanicsM = stats.Int64(
+	"http_handler_panics",
+	"Number of panics recovered in the adapter HTTP handlers",
+	stats.UnitDimensionless,
+)
+
+// recoverer is an http.Handler which recovers panics in next, answering
+// them with a 500 instead of crashing the adapter.
+type recoverer struct {
+	next   http.Handler
+	logger *zap.SugaredLogger
+	// panics is the number of panics recovered, accessed atomically.
+	panics int64
+}
+
+func newRecoverer(next http.Handler, logger *zap.SugaredLogger) *recoverer {
+	if err := view.Register(&view.View{
+		Description: handlerPanicsM.Description(),
+		Measure:     handlerPanicsM,
+		Aggregation: view.Count(),
+	}); err != nil {
+		logger.Errorw("Error registering the HTTP server metrics view", zap.Error(err))
+	}
+	return &recoverer{next: next, logger: logger}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *recoverer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer func() {
+		err := recover()
+		if err == nil {
+			return
+		}
+		if err == http.ErrAbortHandler {
+			// Let the server abort the response.
+			panic(err)
+		}
+		n := atomic.AddInt64(&h.panics, 1)
+		metrics.Record(context.Background(), handlerPanicsM.M(1))
+		h.logger.Errorw("Recovered panic in HTTP handler", zap.String("path", req.URL.Path),
+			zap.String("error", fmt.Sprint(err)), zap.Int64("panics", n), zap.Stack("stack"))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}()
+	h.next.ServeHTTP(w, req)
+}
+
+// whenStarted answers every request with a 503 until the adapter has
+// started, then passes them to next.
+func (a *Adapter) whenStarted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&a.started) == 0 {
+			http.Error(w, "adapter starting", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// serveReady reports the adapter unready while it withholds events. Like
+// every endpoint, it is unready until the adapter has started.
+func (a *Adapter) serveReady(w http.ResponseWriter, req *http.Request) {
+	if dependency := a.degradedDependency(); dependency != "" {
+		http.Error(w, dependency+" unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// serve runs the HTTP server on ln until ctx is cancelled.
+func (a *Adapter) serve(ctx context.Context, ln net.Listener) {
+	go func() {
+		<-ctx.Done()
+		a.server.Shutdown(context.Background())
+	}()
+	a.logger.Infow("Starting HTTP server", zap.String("addr", ln.Addr().String()))
+	if err := a.server.Serve(ln); err != nil && err != http.ErrServerClosed {
+		a.logger.Errorw("HTTP server failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+// freePort returns a port no listener is bound to.
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRecoverer(t *testing.T) {
+	h := newRecoverer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/panic":
+			var m map[string]int
+			m["nil map"]++
+		case "/abort":
+			panic(http.ErrAbortHandler)
+		}
+		w.WriteHeader(http.StatusOK)
+	}), zap.NewNop().Sugar())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&h.panics))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+	assert.Equal(t, int64(1), atomic.LoadInt64(&h.panics), "aborted responses are not panics")
+}
+
+// TestAdapterStartup fires requests continuously at the adapter endpoints
+// while it starts slowly, and checks that they are answered with a 503 until
+// it has started, then with a 200.
+func TestAdapterStartup(t *testing.T) {
+	const startupDelay = 300 * time.Millisecond
+
+	port := freePort(t)
+	srv, _ := newRawSink(t)
+	defer srv.Close()
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	a := NewAdapter(ctx, &envConfig{
+		EnvConfig:        adapter.EnvConfig{Sink: srv.URL},
+		Interval:         time.Hour,
+		HTTPPort:         port,
+		RecentEventsSize: 10,
+	}, newTestClient(t, srv.URL)).(*Adapter)
+	assert.Equal(t, http.StatusServiceUnavailable, ready(a))
+
+	var startedUp int32
+	startup := a.startup
+	a.startup = func(ctx context.Context) {
+		time.Sleep(startupDelay)
+		startup(ctx)
+		atomic.StoreInt32(&startedUp, 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go a.Start(ctx)
+
+	get := func(path string) (int, error) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// The listener is bound before the startup work.
+	require.Eventually(t, func() bool {
+		_, err := get("/readyz")
+		return err == nil
+	}, 5*time.Second, time.Millisecond)
+
+	paths := []string{"/readyz", "/version", "/events"}
+	stop := make(chan struct{})
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		// codes counts the status codes answered by path.
+		codes = make(map[string]map[int]int)
+	)
+	for _, path := range paths {
+		codes[path] = make(map[int]int)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					code, err := get(path)
+					if err != nil {
+						t.Errorf("GET %s: %v", path, err)
+						return
+					}
+					// A 200 can only be answered once the startup work is
+					// done.
+					if code != http.StatusServiceUnavailable &&
+						(code != http.StatusOK || atomic.LoadInt32(&startedUp) == 0) {
+						t.Errorf("GET %s = %d while starting", path, code)
+					}
+					mu.Lock()
+					codes[path][code]++
+					mu.Unlock()
+				}
+			}(path)
+		}
+	}
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&startedUp) == 1
+	}, 5*time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	for _, path := range paths {
+		assert.NotZero(t, codes[path][http.StatusServiceUnavailable], "%s was not probed while starting", path)
+		assert.NotZero(t, codes[path][http.StatusOK], "%s never answered once started", path)
+	}
+	assert.Zero(t, atomic.LoadInt64(&a.server.Handler.(*recoverer).panics))
+}
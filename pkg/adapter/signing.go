@@ -0,0 +1,126 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Sign returns the hex encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signingSecret returns the signing secret configured in env, empty if
+// signing is disabled.
+func signingSecret(env *envConfig) ([]byte, error) {
+	if env.SigningSecretFile == "" {
+		return []byte(env.SigningSecret), nil
+	}
+	secret, err := ioutil.ReadFile(env.SigningSecretFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("signing secret file %q is empty", env.SigningSecretFile)
+	}
+	return secret, nil
+}
+
+// errNoSigningSecret is returned for requests which cannot be signed when
+// failing closed.
+var errNoSigningSecret = errors.New("signing secret unavailable")
+
+// signingTransport is an http.RoundTripper which signs the body of every
+// outbound request and sets the signature on the configured header. The
+// signature is computed over the exact bytes handed to the next transport.
+//
+// The secret is read by load, again on every reload, so that a rotated
+// secret is picked up. While it cannot be read, requests are sent unsigned,
+// or refused when failing closed.
+type signingTransport struct {
+	base       http.RoundTripper
+	header     string
+	load       func() ([]byte, error)
+	failClosed bool
+
+	mu     sync.RWMutex
+	secret []byte
+	err    error
+}
+
+var _ http.RoundTripper = (*signingTransport)(nil)
+
+// reload reads the secret again, and returns the error reading it.
+func (t *signingTransport) reload() error {
+	secret, err := t.load()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.secret, t.err = secret, err
+	return err
+}
+
+// ready reports whether the secret could be read.
+func (t *signingTransport) ready() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.err == nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	secret := t.secret
+	t.mu.RUnlock()
+	if len(secret) == 0 {
+		if !t.failClosed {
+			return t.base.RoundTrip(req)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, errNoSigningSecret
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A RoundTripper must not modify the request it was given.
+	out := req.Clone(req.Context())
+	out.Header.Set(t.header, Sign(secret, body))
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	out.ContentLength = int64(len(body))
+	return t.base.RoundTrip(out)
+}
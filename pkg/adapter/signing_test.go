@@ -0,0 +1,229 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// newRawSink returns a test server recording the raw requests it receives.
+func newRawSink(t *testing.T) (*httptest.Server, chan request) {
+	received := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		select {
+		case received <- request{header: r.Header, body: body}:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return srv, received
+}
+
+func TestAdapterSigning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "secret")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("s3cr3t"), 0600))
+
+	for name, env := range map[string]envConfig{
+		"secret from env":  {SigningSecret: "s3cr3t"},
+		"secret from file": {SigningSecretFile: secretFile},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, received := newRawSink(t)
+			defer srv.Close()
+
+			env.EnvConfig = adapter.EnvConfig{Sink: srv.URL}
+			env.Interval = time.Millisecond
+			env.SignatureHeader = "X-Test-Signature"
+
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &env, nil)
+			ctx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			go func() {
+				a.Start(ctx)
+				close(done)
+			}()
+			// Stop sending before the sink is closed, so that no event
+			// reaches a later test's sink.
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			select {
+			case r := <-received:
+				require.NotEmpty(t, r.body)
+				mac := hmac.New(sha256.New, []byte("s3cr3t"))
+				mac.Write(r.body)
+				assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.header.Get("X-Test-Signature"))
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for event")
+			}
+		})
+	}
+}
+
+func TestSigningTransport(t *testing.T) {
+	srv, received := newRawSink(t)
+	defer srv.Close()
+
+	c := &http.Client{Transport: &signingTransport{
+		base:   http.DefaultTransport,
+		secret: []byte("s3cr3t"),
+		header: "X-Signature-256",
+	}}
+
+	for name, body := range map[string]string{
+		"empty body": "",
+		"json body":  `{"sequence":1,"heartbeat":"10s"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, srv.URL, nil)
+			require.NoError(t, err)
+			if body != "" {
+				req.Body = ioutil.NopCloser(strings.NewReader(body))
+			}
+			resp, err := c.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			r := <-received
+			assert.Equal(t, body, string(r.body))
+			assert.Equal(t, Sign([]byte("s3cr3t"), r.body), r.header.Get("X-Signature-256"))
+			assert.NotEqual(t, Sign([]byte("other"), r.body), r.header.Get("X-Signature-256"))
+			assert.Empty(t, req.Header.Get("X-Signature-256"), "original request must not be modified")
+		})
+	}
+}
+
+func TestSigningTransportWithoutSecret(t *testing.T) {
+	srv, received := newRawSink(t)
+	defer srv.Close()
+
+	for _, failClosed := range []bool{false, true} {
+		tr := &signingTransport{
+			base:       http.DefaultTransport,
+			header:     "X-Signature-256",
+			load:       func() ([]byte, error) { return nil, errors.New("unreadable") },
+			failClosed: failClosed,
+		}
+		assert.Error(t, tr.reload())
+		assert.False(t, tr.ready())
+
+		req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("body"))
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: tr}).Do(req)
+		if failClosed {
+			assert.True(t, errors.Is(err, errNoSigningSecret), "unsigned request sent failing closed: %v", err)
+			continue
+		}
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, (<-received).header.Get("X-Signature-256"))
+	}
+	assert.Empty(t, received)
+}
+
+func TestSigningFailurePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		policy string
+		// wantUnsigned is the number of unsigned events the sink receives
+		// while the secret cannot be read.
+		wantUnsigned int
+		wantReady    int
+	}{
+		"open sends unsigned events": {
+			policy:       failurePolicyOpen,
+			wantUnsigned: 2,
+			wantReady:    http.StatusOK,
+		},
+		"closed withholds events": {
+			policy:    failurePolicyClosed,
+			wantReady: http.StatusServiceUnavailable,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "signing")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			secretFile := filepath.Join(dir, "secret")
+
+			srv, received := newRawSink(t)
+			defer srv.Close()
+			ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+			a := NewAdapter(ctx, &envConfig{
+				EnvConfig:         adapter.EnvConfig{Sink: srv.URL},
+				Interval:          time.Millisecond,
+				SigningSecretFile: secretFile,
+				SignatureHeader:   "X-Test-Signature",
+				FailurePolicy:     tc.policy,
+				SinkFailurePolicy: policyFailFast,
+				SinkBufferSize:    10,
+			}, newTestClient(t, srv.URL)).(*Adapter)
+			atomic.StoreInt32(&a.started, 1)
+
+			// The secret cannot be read.
+			a.tick(ctx)
+			a.tick(ctx)
+			assert.Len(t, received, tc.wantUnsigned)
+			for i := 0; i < tc.wantUnsigned; i++ {
+				assert.Empty(t, (<-received).header.Get("X-Test-Signature"))
+			}
+			assert.Equal(t, tc.wantReady, ready(a))
+
+			// The secret is read again on the next tick. Withheld events are
+			// then sent, signed.
+			require.NoError(t, ioutil.WriteFile(secretFile, []byte("s3cr3t"), 0600))
+			a.tick(ctx)
+			assert.Len(t, received, 3-tc.wantUnsigned)
+			for len(received) > 0 {
+				r := <-received
+				assert.Equal(t, Sign([]byte("s3cr3t"), r.body), r.header.Get("X-Test-Signature"))
+			}
+			assert.Equal(t, http.StatusOK, ready(a))
+			assert.Empty(t, a.buffer)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"go.uber.org/zap"
+	"knative.dev/pkg/metrics"
+)
+
+// Final outcomes of events.
+const (
+	// outcomeDelivered is the outcome of events acknowledged by the sink.
+	outcomeDelivered = "delivered"
+	// outcomeDeadlettered is the outcome of events acknowledged by the
+	// fallback URI.
+	outcomeDeadlettered = "deadlettered"
+	// outcomeDropped is the outcome of events given up on: invalid events,
+	// and events neither the sink nor the fallback URI acknowledged.
+	outcomeDropped = "dropped"
+	// outcomeLost is the outcome of events evicted from the full buffer, or
+	// still buffered when the adapter shuts down.
+	outcomeLost = "lost"
+)
+
+// sloWindow is the window of the rolling SLO ratio, in one minute buckets.
+const sloWindow = 60
+
+// sloPublishInterval is the interval at which the rolling SLO ratio is
+// published, so that outcomes age out of it while no new one is recorded.
+const sloPublishInterval = time.Minute
+
+var (
+	sloEventsM = stats.Int64(
+		"slo_events",
+		"Number of events by final outcome (delivered, deadlettered, dropped or lost), "+
+			"and by whether they reached the sink or the fallback URI within the SLO target "+
+			"from their creation (within_slo). Dropped and lost events are never within the SLO",
+		stats.UnitDimensionless,
+	)
+	sloRatioM = stats.Float64(
+		"slo_ratio",
+		"Ratio of the events reaching a final outcome over the last hour which were "+
+			"delivered or deadlettered within the SLO target. The hour is made of one minute "+
+			"buckets, and the ratio is updated with every outcome and at least every minute, "+
+			"so that outcomes age out while no event reaches a final outcome. It is 1 if no "+
+			"event reached a final outcome over the last hour",
+		stats.UnitDimensionless,
+	)
+
+	outcomeKey   = tag.MustNewKey("outcome")
+	withinSLOKey = tag.MustNewKey("within_slo")
+)
+
+// sloBucket counts the final outcomes of one minute.
+type sloBucket struct {
+	minute int64
+	total  int64
+	within int64
+}
+
+// sloTracker accounts for the final outcome of every event, measured against
+// the SLO target from the creation of the event.
+type sloTracker struct {
+	target time.Duration
+	now    func() time.Time
+	// recordRatio publishes the rolling ratio.
+	recordRatio func(float64)
+
+	mu      sync.Mutex
+	buckets [sloWindow]sloBucket
+	// counts is the number of events by outcome since startup.
+	counts map[string]int64
+}
+
+func newSLOTracker(target time.Duration, logger *zap.SugaredLogger) *sloTracker {
+	if err := view.Register(&view.View{
+		Description: sloEventsM.Description(),
+		Measure:     sloEventsM,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{outcomeKey, withinSLOKey},
+	}, &view.View{
+		Description: sloRatioM.Description(),
+		Measure:     sloRatioM,
+		Aggregation: view.LastValue(),
+	}); err != nil {
+		logger.Errorw("Error registering the SLO metrics views", zap.Error(err))
+	}
+	return &sloTracker{
+		target: target,
+		now:    time.Now,
+		recordRatio: func(ratio float64) {
+			metrics.Record(context.Background(), sloRatioM.M(ratio))
+		},
+		counts: make(map[string]int64),
+	}
+}
+
+// run publishes the rolling ratio every sloPublishInterval until ctx is
+// cancelled.
+func (s *sloTracker) run(ctx context.Context) {
+	ticker := time.NewTicker(sloPublishInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.publish()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publish publishes the rolling ratio over the window ending now.
+func (s *sloTracker) publish() {
+	s.recordRatio(s.ratio(s.now()))
+}
+
+// record accounts for the final outcome of event.
+func (s *sloTracker) record(event cloudevents.Event, outcome string) {
+	now := s.now()
+	within := (outcome == outcomeDelivered || outcome == outcomeDeadlettered) &&
+		now.Sub(event.Time()) <= s.target
+
+	ctx, err := tag.New(context.Background(),
+		tag.Insert(outcomeKey, outcome),
+		tag.Insert(withinSLOKey, strconv.FormatBool(within)))
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, sloEventsM.M(1))
+	s.recordRatio(s.add(now, outcome, within))
+}
+
+// add counts an outcome at now, and returns the ratio over the window.
+func (s *sloTracker) add(now time.Time, outcome string, within bool) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[outcome]++
+
+	minute := now.Unix() / 60
+	b := &s.buckets[minute%sloWindow]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.total++
+	if within {
+		b.within++
+	}
+	return s.ratioLocked(minute)
+}
+
+// count returns the number of events with outcome since startup.
+func (s *sloTracker) count(outcome string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[outcome]
+}
+
+// ratio returns the ratio over the window ending at now, 1 if no event
+// reached a final outcome.
+func (s *sloTracker) ratio(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ratioLocked(now.Unix() / 60)
+}
+
+func (s *sloTracker) ratioLocked(minute int64) float64 {
+	var total, within int64
+	for _, b := range s.buckets {
+		if b.minute > minute-sloWindow && b.minute <= minute {
+			total += b.total
+			within += b.within
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(within) / float64(total)
+}
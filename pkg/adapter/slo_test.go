@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/logging"
+)
+
+func TestSLOTracker(t *testing.T) {
+	start := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	s := newSLOTracker(time.Second, zap.NewNop().Sugar())
+	s.now = func() time.Time { return now }
+
+	eventAt := func(created time.Time) cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetTime(created)
+		return event
+	}
+
+	assert.Equal(t, 1.0, s.ratio(now), "no outcome yet")
+
+	s.record(eventAt(now.Add(-500*time.Millisecond)), outcomeDelivered)
+	s.record(eventAt(now.Add(-time.Second)), outcomeDeadlettered)
+	assert.Equal(t, 1.0, s.ratio(now), "within the target")
+
+	// Late, dropped and lost events are not within the SLO.
+	s.record(eventAt(now.Add(-2*time.Second)), outcomeDelivered)
+	s.record(eventAt(now), outcomeDropped)
+	s.record(eventAt(now), outcomeLost)
+	s.record(eventAt(now), outcomeLost)
+	assert.Equal(t, 2.0/6, s.ratio(now))
+
+	now = start.Add(30 * time.Minute)
+	s.record(eventAt(now), outcomeDelivered)
+	s.record(eventAt(now), outcomeDelivered)
+	assert.Equal(t, 4.0/8, s.ratio(now))
+
+	// The outcomes of the first minute leave the window after an hour.
+	now = start.Add(time.Hour)
+	assert.Equal(t, 1.0, s.ratio(now))
+	now = start.Add(time.Hour + 30*time.Minute)
+	assert.Equal(t, 1.0, s.ratio(now), "no outcome in the window")
+
+	assert.Equal(t, int64(4), s.count(outcomeDelivered))
+	assert.Equal(t, int64(1), s.count(outcomeDeadlettered))
+	assert.Equal(t, int64(1), s.count(outcomeDropped))
+	assert.Equal(t, int64(2), s.count(outcomeLost))
+}
+
+func TestSLORatioAgesOut(t *testing.T) {
+	start := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	s := newSLOTracker(time.Second, zap.NewNop().Sugar())
+	s.now = func() time.Time { return now }
+	var published []float64
+	s.recordRatio = func(ratio float64) { published = append(published, ratio) }
+
+	late := cloudevents.NewEvent()
+	late.SetTime(now.Add(-time.Minute))
+	s.record(late, outcomeDelivered)
+	s.record(late, outcomeLost)
+	assert.Equal(t, []float64{0, 0}, published, "published with every outcome")
+
+	// No outcome is recorded anymore, the published ratio ages out.
+	now = start.Add(30 * time.Minute)
+	s.publish()
+	now = start.Add(time.Hour + time.Minute)
+	s.publish()
+	assert.Equal(t, []float64{0, 0, 0, 1}, published)
+}
+
+func TestAdapterShutdownLosesBufferedEvents(t *testing.T) {
+	sink := newFlakySink()
+	defer sink.Close()
+	sink.setReady(false)
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	a := NewAdapter(ctx, &envConfig{
+		EnvConfig:         adapter.EnvConfig{Sink: sink.URL},
+		Interval:          time.Millisecond,
+		SinkFailurePolicy: policyBuffer,
+		SinkBufferSize:    10,
+	}, newTestClient(t, sink.URL)).(*Adapter)
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		a.Start(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+	// Events are lost either to buffer overflow or to the shutdown.
+	assert.NotZero(t, a.nextID)
+	assert.Equal(t, int64(a.nextID), a.slo.count(outcomeLost))
+	assert.Zero(t, a.slo.count(outcomeDelivered))
+}
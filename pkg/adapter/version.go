@@ -0,0 +1,64 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/discovery"
+)
+
+// Version is the build version of the adapter, set at link time with
+// -ldflags "-X knative.dev/sample-source/pkg/adapter.Version=<version>".
+var Version = "devel"
+
+// versionExtension is the CloudEvents extension carrying Version.
+const versionExtension = "adapterversion"
+
+// versionInfo is the body of the /version endpoint.
+type versionInfo struct {
+	Adapter    string `json:"adapter"`
+	Kubernetes string `json:"kubernetes,omitempty"`
+}
+
+// versionHandler serves the adapter version, and the Kubernetes version
+// when the adapter runs with a Kubernetes client.
+type versionHandler struct {
+	// kube is nil if no Kubernetes client is available.
+	kube   discovery.ServerVersionInterface
+	logger *zap.SugaredLogger
+}
+
+// ServeHTTP serves versionInfo as JSON.
+func (h *versionHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := versionInfo{Adapter: Version}
+	if h.kube != nil {
+		v, err := h.kube.ServerVersion()
+		if err != nil {
+			h.logger.Warnw("Failed to get the Kubernetes version", zap.Error(err))
+		} else {
+			info.Kubernetes = v.GitVersion
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+		http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/logging"
+
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+)
+
+func TestVersionHandler(t *testing.T) {
+	testCases := map[string]struct {
+		handler *versionHandler
+		want    versionInfo
+	}{
+		"with kubernetes client": {
+			handler: &versionHandler{
+				kube: &fakediscovery.FakeDiscovery{
+					Fake:               &k8stesting.Fake{},
+					FakedServerVersion: &version.Info{GitVersion: "v1.20.2"},
+				},
+				logger: zap.NewNop().Sugar(),
+			},
+			want: versionInfo{Adapter: Version, Kubernetes: "v1.20.2"},
+		},
+		"without kubernetes client": {
+			handler: &versionHandler{logger: zap.NewNop().Sugar()},
+			want:    versionInfo{Adapter: Version},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			got := versionInfo{}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	(&versionHandler{}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/version", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestAdapterVersion(t *testing.T) {
+	old := Version
+	Version = "v0.0.1-test"
+	defer func() { Version = old }()
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	ctx, kc := fakekubeclient.With(ctx)
+	kc.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.20.2"}
+
+	a := NewAdapter(ctx, &envConfig{Interval: time.Millisecond}, nil).(*Adapter)
+	assert.Equal(t, "v0.0.1-test", a.newEvent().Extensions()[versionExtension])
+
+	// The endpoints answer once the adapter has started.
+	atomic.StoreInt32(&a.started, 1)
+	w := httptest.NewRecorder()
+	a.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	got := versionInfo{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, versionInfo{Adapter: "v0.0.1-test", Kubernetes: "v1.20.2"}, got)
+}
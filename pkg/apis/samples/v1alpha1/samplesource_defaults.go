@@ -22,6 +22,10 @@ import (
 	"knative.dev/pkg/apis"
 )
 
+// DefaultSignatureHeader is the header carrying the event signature when
+// signing is enabled and no header is specified.
+const DefaultSignatureHeader = "X-Signature-256"
+
 // SetDefaults mutates SampleSource.
 func (s *SampleSource) SetDefaults(ctx context.Context) {
 	//Add code for Mutating admission webhook.
@@ -36,7 +40,45 @@ func (s *SampleSource) SetDefaults(ctx context.Context) {
 		s.Spec.Interval = "10s"
 	}
 
+	//example: If a signing header is unspecified, default to "X-Signature-256".
+	if s != nil && s.Spec.Signing != nil && s.Spec.Signing.Header == "" {
+		s.Spec.Signing.Header = DefaultSignatureHeader
+	}
+
+	//example: If the compression threshold is unspecified, default to 1024 bytes.
+	if s != nil && s.Spec.Compression != nil && s.Spec.Compression.Threshold == 0 {
+		s.Spec.Compression.Threshold = 1024
+	}
+
+	//example: If the retention age of recent events is unspecified, default to "1h".
+	if s != nil && s.Spec.RecentEvents != nil && s.Spec.RecentEvents.MaxAge == "" {
+		s.Spec.RecentEvents.MaxAge = "1h"
+	}
+
+	//example: If the sink failure policy or buffer size are unspecified, default to "FailFast" and 100.
+	if s != nil && s.Spec.SinkFailure != nil {
+		if s.Spec.SinkFailure.Policy == "" {
+			s.Spec.SinkFailure.Policy = SinkFailurePolicyFailFast
+		}
+		if s.Spec.SinkFailure.Policy == SinkFailurePolicyBuffer && s.Spec.SinkFailure.BufferSize == 0 {
+			s.Spec.SinkFailure.BufferSize = 100
+		}
+	}
+
+	//example: If the invalid event policy is unspecified, default to "Drop".
+	if s != nil && s.Spec.InvalidEventPolicy == "" {
+		s.Spec.InvalidEventPolicy = InvalidEventPolicyDrop
+	}
+
+	//example: If the failure policy is unspecified, default to "Open".
+	if s != nil && s.Spec.FailurePolicy == "" {
+		s.Spec.FailurePolicy = FailurePolicyOpen
+	}
+
 	// call SetDefaults against duckv1.Destination with a context of ObjectMeta of SampleSource.
 	withNS := apis.WithinParent(ctx, s.ObjectMeta)
 	s.Spec.Sink.SetDefaults(withNS)
+	if s.Spec.AuditSink != nil {
+		s.Spec.AuditSink.SetDefaults(withNS)
+	}
 }
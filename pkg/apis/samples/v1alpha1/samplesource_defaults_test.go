@@ -37,6 +37,8 @@ func TestSampleSourceDefaults(t *testing.T) {
 				Spec: SampleSourceSpec{
 					ServiceAccountName: "default",
 					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
 				},
 			},
 		},
@@ -62,6 +64,8 @@ func TestSampleSourceDefaults(t *testing.T) {
 				Spec: SampleSourceSpec{
 					ServiceAccountName: "default",
 					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
 					SourceSpec: duckv1.SourceSpec{
 						Sink: duckv1.Destination{
 							Ref: &duckv1.KReference{
@@ -72,6 +76,118 @@ func TestSampleSourceDefaults(t *testing.T) {
 				},
 			},
 		},
+		"no signing header": {
+			initial: SampleSource{
+				Spec: SampleSourceSpec{
+					Signing: &SigningSpec{},
+				},
+			},
+			expected: SampleSource{
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					Signing: &SigningSpec{
+						Header: "X-Signature-256",
+					},
+				},
+			},
+		},
+		"no compression threshold": {
+			initial: SampleSource{
+				Spec: SampleSourceSpec{
+					Compression: &CompressionSpec{},
+				},
+			},
+			expected: SampleSource{
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					Compression:        &CompressionSpec{Threshold: 1024},
+				},
+			},
+		},
+		"no recent events max age": {
+			initial: SampleSource{
+				Spec: SampleSourceSpec{
+					RecentEvents: &RecentEventsSpec{Size: 100},
+				},
+			},
+			expected: SampleSource{
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					RecentEvents: &RecentEventsSpec{
+						Size:   100,
+						MaxAge: "1h",
+					},
+				},
+			},
+		},
+		"buffer sink failure policy": {
+			initial: SampleSource{
+				Spec: SampleSourceSpec{
+					SinkFailure: &SinkFailureSpec{Policy: SinkFailurePolicyBuffer},
+				},
+			},
+			expected: SampleSource{
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					SinkFailure: &SinkFailureSpec{
+						Policy:     SinkFailurePolicyBuffer,
+						BufferSize: 100,
+					},
+				},
+			},
+		},
+		"no sink failure policy": {
+			initial: SampleSource{
+				Spec: SampleSourceSpec{
+					SinkFailure: &SinkFailureSpec{},
+				},
+			},
+			expected: SampleSource{
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					SinkFailure: &SinkFailureSpec{
+						Policy: SinkFailurePolicyFailFast,
+					},
+				},
+			},
+		},
+		"audit sink namespace": {
+			initial: SampleSource{
+				ObjectMeta: v1.ObjectMeta{Namespace: "ns"},
+				Spec: SampleSourceSpec{
+					AuditSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{Kind: "Broker", Name: "audit"},
+					},
+				},
+			},
+			expected: SampleSource{
+				ObjectMeta: v1.ObjectMeta{Namespace: "ns"},
+				Spec: SampleSourceSpec{
+					ServiceAccountName: "default",
+					Interval:           "10s",
+					InvalidEventPolicy: InvalidEventPolicyDrop,
+					FailurePolicy:      FailurePolicyOpen,
+					AuditSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{Kind: "Broker", Namespace: "ns", Name: "audit"},
+					},
+				},
+			},
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
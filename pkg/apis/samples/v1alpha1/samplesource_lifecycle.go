@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
 	"knative.dev/eventing/pkg/apis/duck"
 	"knative.dev/pkg/apis"
@@ -31,6 +33,11 @@ const (
 
 	// SampleConditionDeployed has status True when the SampleSource has had it's deployment created.
 	SampleConditionDeployed apis.ConditionType = "Deployed"
+
+	// SampleConditionSinkSwitched has status Unknown while the receive adapter sending to the
+	// previous sink drains its events after a change of sink, and True once it is done. It is
+	// not set until the sink first changes, and does not affect readiness.
+	SampleConditionSinkSwitched apis.ConditionType = "SinkSwitched"
 )
 
 var SampleCondSet = apis.NewLivingConditionSet(
@@ -68,6 +75,20 @@ func (s *SampleSourceStatus) MarkNoSink(reason, messageFormat string, messageA .
 	SampleCondSet.Manage(s).MarkFalse(SampleConditionSinkProvided, reason, messageFormat, messageA...)
 }
 
+// MarkSinkSwitching sets the condition that the receive adapter sending to the previous sink
+// drains its events for up to timeout.
+func (s *SampleSourceStatus) MarkSinkSwitching(previous *apis.URL, timeout time.Duration) {
+	s.PreviousSinkURI = previous
+	SampleCondSet.Manage(s).MarkUnknown(SampleConditionSinkSwitched, "Draining",
+		"The receive adapter sending to %s drains its events for up to %s.", previous, timeout)
+}
+
+// MarkSinkSwitched sets the condition that the events for the previous sink have been drained.
+func (s *SampleSourceStatus) MarkSinkSwitched() {
+	s.PreviousSinkURI = nil
+	SampleCondSet.Manage(s).MarkTrue(SampleConditionSinkSwitched)
+}
+
 // PropagateDeploymentAvailability uses the availability of the provided Deployment to determine if
 // SampleConditionDeployed should be marked as true or false.
 func (s *SampleSourceStatus) PropagateDeploymentAvailability(d *appsv1.Deployment) {
@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -87,8 +90,174 @@ type SampleSourceSpec struct {
 	// units are "ns", "us" (or "µs"), "ms", "s", "m", "h". If unspecified
 	// this will default to "10s".
 	Interval string `json:"interval"`
+
+	// Signing configures HMAC signing of the body of each event sent to the
+	// sink, so that the sink can verify the events originate from this source.
+	// +optional
+	Signing *SigningSpec `json:"signing,omitempty"`
+
+	// Compression configures gzip compression of the body of events sent to
+	// the sink. Bodies are sent uncompressed if unspecified.
+	// +optional
+	Compression *CompressionSpec `json:"compression,omitempty"`
+
+	// RecentEvents configures the retention of the ids of recently sent
+	// events, which can be looked up on the receive adapter's /events
+	// endpoint when troubleshooting deliveries.
+	// +optional
+	RecentEvents *RecentEventsSpec `json:"recentEvents,omitempty"`
+
+	// SinkFailure configures how the receive adapter handles events the sink
+	// does not acknowledge, for example while a Broker is not ready. If
+	// unspecified such events are dropped.
+	// +optional
+	SinkFailure *SinkFailureSpec `json:"sinkFailure,omitempty"`
+
+	// InvalidEventPolicy is the handling of events which are not valid
+	// CloudEvents, which are never sent to the sink. One of "Drop" or
+	// "Fallback", which reports them to the fallback URI of SinkFailure. If
+	// unspecified this will default to "Drop".
+	// +optional
+	InvalidEventPolicy InvalidEventPolicy `json:"invalidEventPolicy,omitempty"`
+
+	// FailurePolicy is the handling of failures of the best-effort features,
+	// signing and auditing. "Open" keeps sending events, unsigned or
+	// unaudited. "Closed" withholds events until the feature recovers. If
+	// unspecified this will default to "Open".
+	// +optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// AuditSink is where the receive adapter sends an audit event for every
+	// delivery outcome. Audit events describe the outcome and never carry
+	// the data of the event. Auditing is best-effort and disabled if
+	// unspecified.
+	// +optional
+	AuditSink *duckv1.Destination `json:"auditSink,omitempty"`
+
+	// SLOTarget is the time from the creation of an event within which it
+	// should reach the sink or the fallback URI, in the same format as
+	// Interval. The receive adapter reports the ratio of events meeting it.
+	// If unspecified the receive adapter uses "30s".
+	// +optional
+	SLOTarget string `json:"sloTarget,omitempty"`
+}
+
+// SigningSpec configures HMAC-SHA256 signing of outbound event bodies.
+type SigningSpec struct {
+	// SecretKeyRef references the key of a Secret holding the shared secret
+	// used to compute the signature.
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef"`
+
+	// Header is the name of the HTTP header carrying the hex encoded
+	// signature. If unspecified this will default to "X-Signature-256".
+	// +optional
+	Header string `json:"header,omitempty"`
 }
 
+// CompressionSpec configures gzip compression of outbound event bodies.
+type CompressionSpec struct {
+	// Threshold is the size in bytes above which a body is compressed. If
+	// unspecified this will default to 1024.
+	// +optional
+	Threshold int32 `json:"threshold,omitempty"`
+
+	// AssumeSupported compresses bodies from the first event on. Otherwise
+	// bodies are only compressed once the sink has advertised gzip support
+	// in the Accept-Encoding header of a response.
+	// +optional
+	AssumeSupported bool `json:"assumeSupported,omitempty"`
+}
+
+// RecentEventsSpec bounds the retention of recently sent event ids.
+type RecentEventsSpec struct {
+	// Size is the maximum number of event ids retained.
+	Size int32 `json:"size"`
+
+	// MaxAge is the maximum age of a retained event id, in the same format as
+	// Interval. If unspecified this will default to "1h".
+	// +optional
+	MaxAge string `json:"maxAge,omitempty"`
+}
+
+// SinkFailurePolicy is the handling of events the sink does not acknowledge.
+type SinkFailurePolicy string
+
+const (
+	// SinkFailurePolicyFailFast drops the event.
+	SinkFailurePolicyFailFast SinkFailurePolicy = "FailFast"
+
+	// SinkFailurePolicyBuffer keeps the event in a bounded buffer, and
+	// retries the buffered events before sending each new event.
+	SinkFailurePolicyBuffer SinkFailurePolicy = "Buffer"
+
+	// SinkFailurePolicyFallback sends the event to a static fallback URI.
+	SinkFailurePolicyFallback SinkFailurePolicy = "Fallback"
+)
+
+// SinkFailureSpec configures the handling of events the sink does not
+// acknowledge.
+type SinkFailureSpec struct {
+	// Policy is one of "FailFast", "Buffer" or "Fallback". If unspecified
+	// this will default to "FailFast".
+	// +optional
+	Policy SinkFailurePolicy `json:"policy,omitempty"`
+
+	// BufferSize is the maximum number of events kept by the Buffer policy.
+	// The oldest event is dropped when the buffer is full. If unspecified
+	// this will default to 100.
+	// +optional
+	BufferSize int32 `json:"bufferSize,omitempty"`
+
+	// FallbackURI is where the Fallback policy sends events to, and the
+	// Fallback invalid event policy reports invalid events to. It must be
+	// an absolute URI.
+	// +optional
+	FallbackURI *apis.URL `json:"fallbackUri,omitempty"`
+
+	// DrainTimeout bounds how long a receive adapter being replaced, for
+	// example after a change of sink, keeps retrying its buffered events to
+	// the sink it was started with. The events left are then sent to
+	// FallbackURI, if set. It is in the same format as Interval, and at most
+	// "5m". If unspecified the receive adapter uses "5s".
+	// +optional
+	DrainTimeout string `json:"drainTimeout,omitempty"`
+}
+
+const (
+	// DefaultDrainTimeout is the drain timeout of the receive adapter if
+	// DrainTimeout is unspecified.
+	DefaultDrainTimeout = 5 * time.Second
+
+	// MaxDrainTimeout bounds DrainTimeout. The termination grace period of
+	// the receive adapter grows with it.
+	MaxDrainTimeout = 5 * time.Minute
+)
+
+// InvalidEventPolicy is the handling of events which are not valid
+// CloudEvents.
+type InvalidEventPolicy string
+
+const (
+	// InvalidEventPolicyDrop drops the event.
+	InvalidEventPolicyDrop InvalidEventPolicy = "Drop"
+
+	// InvalidEventPolicyFallback sends an event describing why the event is
+	// invalid to the fallback URI.
+	InvalidEventPolicyFallback InvalidEventPolicy = "Fallback"
+)
+
+// FailurePolicy is the handling of failures of best-effort features.
+type FailurePolicy string
+
+const (
+	// FailurePolicyOpen keeps sending events when a best-effort feature
+	// fails.
+	FailurePolicyOpen FailurePolicy = "Open"
+
+	// FailurePolicyClosed withholds events while a best-effort feature fails.
+	FailurePolicyClosed FailurePolicy = "Closed"
+)
+
 const (
 	// SampleSourceConditionReady is set when the revision is starting to materialize
 	// runtime resources, and becomes true when those resources are ready.
@@ -105,6 +274,12 @@ type SampleSourceStatus struct {
 	// * SinkURI - the current active sink URI that has been configured for the
 	//   Source.
 	duckv1.SourceStatus `json:",inline"`
+
+	// PreviousSinkURI is the sink URI the Source sent events to before its
+	// sink changed, while the receive adapter started with it drains its
+	// events. It is cleared once the SinkSwitched condition is True.
+	// +optional
+	PreviousSinkURI *apis.URL `json:"previousSinkUri,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -18,6 +18,8 @@ package v1alpha1
 
 import (
 	"context"
+	"math"
+	"strings"
 	"time"
 
 	"knative.dev/pkg/apis"
@@ -54,5 +56,134 @@ func (sspec *SampleSourceSpec) Validate(ctx context.Context) *apis.FieldError {
 		errs = errs.Also(apis.ErrMissingField("serviceAccountName"))
 	}
 
+	if sspec.Signing != nil {
+		errs = errs.Also(sspec.Signing.Validate(ctx).ViaField("signing"))
+	}
+
+	if sspec.Compression != nil && sspec.Compression.Threshold < 0 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(sspec.Compression.Threshold, 0, math.MaxInt32, "compression.threshold"))
+	}
+
+	if sspec.RecentEvents != nil {
+		errs = errs.Also(sspec.RecentEvents.Validate(ctx).ViaField("recentEvents"))
+	}
+
+	if sspec.SinkFailure != nil {
+		errs = errs.Also(sspec.SinkFailure.Validate(ctx).ViaField("sinkFailure"))
+	}
+
+	switch sspec.InvalidEventPolicy {
+	case "", InvalidEventPolicyDrop:
+	case InvalidEventPolicyFallback:
+		if sspec.SinkFailure == nil || sspec.SinkFailure.FallbackURI == nil {
+			errs = errs.Also(apis.ErrMissingField("sinkFailure.fallbackUri"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(sspec.InvalidEventPolicy, "invalidEventPolicy"))
+	}
+
+	switch sspec.FailurePolicy {
+	case "", FailurePolicyOpen, FailurePolicyClosed:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(sspec.FailurePolicy, "failurePolicy"))
+	}
+
+	if sspec.AuditSink != nil {
+		if fe := sspec.AuditSink.Validate(ctx); fe != nil {
+			errs = errs.Also(fe.ViaField("auditSink"))
+		}
+	}
+
+	if sspec.SLOTarget != "" {
+		if d, err := time.ParseDuration(sspec.SLOTarget); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err, "sloTarget"))
+		} else if d <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(sspec.SLOTarget, "sloTarget"))
+		}
+	}
+
 	return errs
 }
+
+// Validate validates SigningSpec.
+func (ss *SigningSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if ref := ss.SecretKeyRef; ref == nil {
+		errs = errs.Also(apis.ErrMissingField("secretKeyRef"))
+	} else {
+		if ref.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("secretKeyRef.name"))
+		}
+		if ref.Key == "" {
+			errs = errs.Also(apis.ErrMissingField("secretKeyRef.key"))
+		}
+	}
+
+	if ss.Header == "" {
+		errs = errs.Also(apis.ErrMissingField("header"))
+	} else if !isHeaderName(ss.Header) {
+		errs = errs.Also(apis.ErrInvalidValue(ss.Header, "header"))
+	}
+
+	return errs
+}
+
+// Validate validates RecentEventsSpec.
+func (rs *RecentEventsSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	if rs.Size <= 0 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(rs.Size, 1, math.MaxInt32, "size"))
+	}
+
+	if d, err := time.ParseDuration(rs.MaxAge); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(err, "maxAge"))
+	} else if d <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(rs.MaxAge, "maxAge"))
+	}
+
+	return errs
+}
+
+// Validate validates SinkFailureSpec.
+func (sf *SinkFailureSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	switch sf.Policy {
+	case SinkFailurePolicyFailFast:
+	case SinkFailurePolicyBuffer:
+		if sf.BufferSize <= 0 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(sf.BufferSize, 1, math.MaxInt32, "bufferSize"))
+		}
+	case SinkFailurePolicyFallback:
+		if sf.FallbackURI == nil {
+			errs = errs.Also(apis.ErrMissingField("fallbackUri"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(sf.Policy, "policy"))
+	}
+
+	// The fallback URI is also used by the Fallback invalid event policy.
+	if sf.FallbackURI != nil && (!sf.FallbackURI.URL().IsAbs() || sf.FallbackURI.Host == "") {
+		errs = errs.Also(apis.ErrInvalidValue(sf.FallbackURI.String(), "fallbackUri"))
+	}
+
+	if sf.DrainTimeout != "" {
+		if d, err := time.ParseDuration(sf.DrainTimeout); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(err, "drainTimeout"))
+		} else if d < 0 || d > MaxDrainTimeout {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(sf.DrainTimeout, "0s", MaxDrainTimeout.String(), "drainTimeout"))
+		}
+	}
+
+	return errs
+}
+
+// isHeaderName reports whether name is a valid HTTP header field name, i.e. a
+// non-empty RFC 7230 token.
+func isHeaderName(name string) bool {
+	return name != "" && strings.IndexFunc(name, func(r rune) bool {
+		return r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+	}) < 0
+}
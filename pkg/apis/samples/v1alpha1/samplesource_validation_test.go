@@ -18,15 +18,24 @@ package v1alpha1
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/webhook/resourcesemantics"
 
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
+var validSourceSpec = duckv1.SourceSpec{
+	Sink: duckv1.Destination{
+		URI: apis.HTTP("example.com"),
+	},
+}
+
 func TestSampleSourceValidation(t *testing.T) {
 	testCases := map[string]struct {
 		cr   resourcesemantics.GenericCRD
@@ -55,6 +64,289 @@ func TestSampleSourceValidation(t *testing.T) {
 				return errs
 			}(),
 		},
+		"invalid signing": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					Signing: &SigningSpec{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "signing"},
+						},
+						Header: "X Signature",
+					},
+				},
+			},
+			want: func() *apis.FieldError {
+				var errs *apis.FieldError
+				errs = errs.Also(apis.ErrMissingField("spec.signing.secretKeyRef.key"))
+				errs = errs.Also(apis.ErrInvalidValue("X Signature", "spec.signing.header"))
+				return errs
+			}(),
+		},
+		"valid signing": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					Signing: &SigningSpec{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "signing"},
+							Key:                  "secret",
+						},
+						Header: "X-Signature-256",
+					},
+				},
+			},
+		},
+		"negative compression threshold": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					Compression:        &CompressionSpec{Threshold: -1},
+				},
+			},
+			want: apis.ErrOutOfBoundsValue(-1, 0, math.MaxInt32, "spec.compression.threshold"),
+		},
+		"invalid slo target": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SLOTarget:          "0s",
+				},
+			},
+			want: apis.ErrInvalidValue("0s", "spec.sloTarget"),
+		},
+		"invalid recent events": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					RecentEvents: &RecentEventsSpec{
+						Size:   0,
+						MaxAge: "-1h",
+					},
+				},
+			},
+			want: func() *apis.FieldError {
+				var errs *apis.FieldError
+				errs = errs.Also(apis.ErrOutOfBoundsValue(0, 1, math.MaxInt32, "spec.recentEvents.size"))
+				errs = errs.Also(apis.ErrInvalidValue("-1h", "spec.recentEvents.maxAge"))
+				return errs
+			}(),
+		},
+		"valid recent events": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					RecentEvents: &RecentEventsSpec{
+						Size:   100,
+						MaxAge: "1h",
+					},
+				},
+			},
+		},
+		"fallback policy without uri": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy: SinkFailurePolicyFallback,
+					},
+				},
+			},
+			want: apis.ErrMissingField("spec.sinkFailure.fallbackUri"),
+		},
+		"unknown sink failure policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy: "Retry",
+					},
+				},
+			},
+			want: apis.ErrInvalidValue("Retry", "spec.sinkFailure.policy"),
+		},
+		"negative drain timeout": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:       SinkFailurePolicyBuffer,
+						BufferSize:   10,
+						DrainTimeout: "-5s",
+					},
+				},
+			},
+			want: apis.ErrOutOfBoundsValue("-5s", "0s", "5m0s", "spec.sinkFailure.drainTimeout"),
+		},
+		"drain timeout too long": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:       SinkFailurePolicyBuffer,
+						BufferSize:   10,
+						DrainTimeout: "1h",
+					},
+				},
+			},
+			want: apis.ErrOutOfBoundsValue("1h", "0s", "5m0s", "spec.sinkFailure.drainTimeout"),
+		},
+		"valid fallback policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:      SinkFailurePolicyFallback,
+						FallbackURI: apis.HTTP("fallback.example.com"),
+					},
+				},
+			},
+		},
+		"relative fallback uri": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:      SinkFailurePolicyFallback,
+						FallbackURI: &apis.URL{Path: "/fallback"},
+					},
+				},
+			},
+			want: apis.ErrInvalidValue("/fallback", "spec.sinkFailure.fallbackUri"),
+		},
+		"relative fallback uri with another policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:      SinkFailurePolicyFailFast,
+						FallbackURI: &apis.URL{Path: "/fallback"},
+					},
+					InvalidEventPolicy: InvalidEventPolicyFallback,
+				},
+			},
+			want: apis.ErrInvalidValue("/fallback", "spec.sinkFailure.fallbackUri"),
+		},
+		"fallback uri without host": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:      SinkFailurePolicyFailFast,
+						FallbackURI: &apis.URL{Scheme: "http", Path: "/fallback"},
+					},
+				},
+			},
+			want: apis.ErrInvalidValue("http:///fallback", "spec.sinkFailure.fallbackUri"),
+		},
+		"unknown invalid event policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					InvalidEventPolicy: "Retry",
+				},
+			},
+			want: apis.ErrInvalidValue("Retry", "spec.invalidEventPolicy"),
+		},
+		"invalid event fallback without fallback uri": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					InvalidEventPolicy: InvalidEventPolicyFallback,
+				},
+			},
+			want: apis.ErrMissingField("spec.sinkFailure.fallbackUri"),
+		},
+		"valid invalid event fallback": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					SinkFailure: &SinkFailureSpec{
+						Policy:      SinkFailurePolicyFailFast,
+						FallbackURI: apis.HTTP("fallback.example.com"),
+					},
+					InvalidEventPolicy: InvalidEventPolicyFallback,
+				},
+			},
+		},
+		"unknown failure policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					FailurePolicy:      "Ignore",
+				},
+			},
+			want: apis.ErrInvalidValue("Ignore", "spec.failurePolicy"),
+		},
+		"closed failure policy": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					FailurePolicy:      FailurePolicyClosed,
+				},
+			},
+		},
+		"invalid audit sink": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					AuditSink:          &duckv1.Destination{},
+				},
+			},
+			want: apis.ErrGeneric("expected at least one, got none", "ref", "uri").ViaField("auditSink").ViaField("spec"),
+		},
+		"valid audit sink": {
+			cr: &SampleSource{
+				Spec: SampleSourceSpec{
+					SourceSpec:         validSourceSpec,
+					Interval:           "10s",
+					ServiceAccountName: "default",
+					AuditSink: &duckv1.Destination{
+						URI: apis.HTTP("audit.example.com"),
+					},
+				},
+			},
+		},
 	}
 
 	for n, test := range testCases {
@@ -21,9 +21,44 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	apis "knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompressionSpec) DeepCopyInto(out *CompressionSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompressionSpec.
+func (in *CompressionSpec) DeepCopy() *CompressionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CompressionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecentEventsSpec) DeepCopyInto(out *RecentEventsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecentEventsSpec.
+func (in *RecentEventsSpec) DeepCopy() *RecentEventsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RecentEventsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SampleSource) DeepCopyInto(out *SampleSource) {
 	*out = *in
@@ -89,6 +124,31 @@ func (in *SampleSourceList) DeepCopyObject() runtime.Object {
 func (in *SampleSourceSpec) DeepCopyInto(out *SampleSourceSpec) {
 	*out = *in
 	in.SourceSpec.DeepCopyInto(&out.SourceSpec)
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(SigningSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Compression != nil {
+		in, out := &in.Compression, &out.Compression
+		*out = new(CompressionSpec)
+		**out = **in
+	}
+	if in.RecentEvents != nil {
+		in, out := &in.RecentEvents, &out.RecentEvents
+		*out = new(RecentEventsSpec)
+		**out = **in
+	}
+	if in.SinkFailure != nil {
+		in, out := &in.SinkFailure, &out.SinkFailure
+		*out = new(SinkFailureSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuditSink != nil {
+		in, out := &in.AuditSink, &out.AuditSink
+		*out = new(duckv1.Destination)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -106,6 +166,11 @@ func (in *SampleSourceSpec) DeepCopy() *SampleSourceSpec {
 func (in *SampleSourceStatus) DeepCopyInto(out *SampleSourceStatus) {
 	*out = *in
 	in.SourceStatus.DeepCopyInto(&out.SourceStatus)
+	if in.PreviousSinkURI != nil {
+		in, out := &in.PreviousSinkURI, &out.PreviousSinkURI
+		*out = new(apis.URL)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -118,3 +183,45 @@ func (in *SampleSourceStatus) DeepCopy() *SampleSourceStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SinkFailureSpec) DeepCopyInto(out *SinkFailureSpec) {
+	*out = *in
+	if in.FallbackURI != nil {
+		in, out := &in.FallbackURI, &out.FallbackURI
+		*out = new(apis.URL)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SinkFailureSpec.
+func (in *SinkFailureSpec) DeepCopy() *SinkFailureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SinkFailureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SigningSpec) DeepCopyInto(out *SigningSpec) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SigningSpec.
+func (in *SigningSpec) DeepCopy() *SigningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SigningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
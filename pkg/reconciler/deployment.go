@@ -45,6 +45,11 @@ func newDeploymentUpdated(namespace, name string) pkgreconciler.Event {
 
 type DeploymentReconciler struct {
 	KubeClientSet kubernetes.Interface
+
+	// OwnedVolumes are the names of the volumes, and of their mounts, which
+	// the controller sets on the receive adapter. The other volumes may have
+	// been added by admission webhooks, and are kept.
+	OwnedVolumes []string
 }
 
 // ReconcileDeployment reconciles deployment resource for SampleSource
@@ -68,7 +73,7 @@ func (r *DeploymentReconciler) ReconcileDeployment(
 	} else if !metav1.IsControlledBy(ra, owner.GetObjectMeta()) {
 		return nil, binder, fmt.Errorf("deployment %q is not owned by %s %q",
 			ra.Name, owner.GetGroupVersionKind().Kind, owner.GetObjectMeta().GetName())
-	} else if podSpecSync(ctx, binder, expected.Spec.Template.Spec, ra.Spec.Template.Spec) {
+	} else if podSpecSync(ctx, binder, expected.Spec.Template.Spec, &ra.Spec.Template.Spec, r.OwnedVolumes) {
 		if ra, err = r.KubeClientSet.AppsV1().Deployments(namespace).Update(ctx, ra, metav1.UpdateOptions{}); err != nil {
 			return ra, binder, err
 		}
@@ -104,13 +109,18 @@ func getContainer(name string, spec corev1.PodSpec) (int, *corev1.Container) {
 	return -1, nil
 }
 
-// Returns true if an update is needed.
-func podSpecSync(ctx context.Context, binder *sourcesv1.SinkBinding, expected corev1.PodSpec, now corev1.PodSpec) bool {
+// Returns true if an update is needed. Only the owned volumes of expected are
+// synced.
+func podSpecSync(ctx context.Context, binder *sourcesv1.SinkBinding, expected corev1.PodSpec, now *corev1.PodSpec, owned []string) bool {
 	old := *now.DeepCopy()
-	syncImage(expected, now)
-	syncSink(ctx, binder, now)
-
-	return !equality.Semantic.DeepEqual(old, now)
+	syncImage(expected, *now)
+	syncEnv(expected, *now)
+	syncReadiness(expected, *now)
+	syncVolumes(expected, now, owned)
+	syncGracePeriod(expected, now)
+	syncSink(ctx, binder, *now)
+
+	return !equality.Semantic.DeepEqual(old, *now)
 }
 
 func syncSink(ctx context.Context, binder *sourcesv1.SinkBinding, now corev1.PodSpec) {
@@ -134,3 +144,90 @@ func syncImage(expected corev1.PodSpec, now corev1.PodSpec) {
 		}
 	}
 }
+
+func syncEnv(expected corev1.PodSpec, now corev1.PodSpec) {
+	// the sink env vars are projected again by syncSink afterwards.
+	for _, ec := range expected.Containers {
+		if n, nc := getContainer(ec.Name, now); nc != nil {
+			now.Containers[n].Env = ec.Env
+		}
+	}
+}
+
+func syncReadiness(expected corev1.PodSpec, now corev1.PodSpec) {
+	// the defaults of the ports and probes are set explicitly by the controller.
+	for _, ec := range expected.Containers {
+		if n, nc := getContainer(ec.Name, now); nc != nil {
+			now.Containers[n].Ports = ec.Ports
+			now.Containers[n].ReadinessProbe = ec.ReadinessProbe
+		}
+	}
+}
+
+func syncGracePeriod(expected corev1.PodSpec, now *corev1.PodSpec) {
+	// the grace period covers the drain timeout of the receive adapter.
+	if expected.TerminationGracePeriodSeconds != nil {
+		now.TerminationGracePeriodSeconds = expected.TerminationGracePeriodSeconds
+	}
+}
+
+func syncVolumes(expected corev1.PodSpec, now *corev1.PodSpec, owned []string) {
+	// only the owned volumes are set by the controller, the others may have
+	// been added by admission webhooks.
+	for _, name := range owned {
+		now.Volumes = syncVolume(name, expected.Volumes, now.Volumes)
+		for _, ec := range expected.Containers {
+			if n, nc := getContainer(ec.Name, *now); nc != nil {
+				now.Containers[n].VolumeMounts = syncVolumeMount(name, ec.VolumeMounts, nc.VolumeMounts)
+			}
+		}
+	}
+}
+
+// syncVolume returns now with the volume name of expected, in place if now
+// has one already.
+func syncVolume(name string, expected, now []corev1.Volume) []corev1.Volume {
+	var want *corev1.Volume
+	for i := range expected {
+		if expected[i].Name == name {
+			want = &expected[i]
+		}
+	}
+	synced := make([]corev1.Volume, 0, len(now)+1)
+	for _, v := range now {
+		if v.Name != name {
+			synced = append(synced, v)
+		} else if want != nil {
+			synced = append(synced, *want)
+			want = nil
+		}
+	}
+	if want != nil {
+		synced = append(synced, *want)
+	}
+	return synced
+}
+
+// syncVolumeMount returns now with the volume mount name of expected, in
+// place if now has one already.
+func syncVolumeMount(name string, expected, now []corev1.VolumeMount) []corev1.VolumeMount {
+	var want *corev1.VolumeMount
+	for i := range expected {
+		if expected[i].Name == name {
+			want = &expected[i]
+		}
+	}
+	synced := make([]corev1.VolumeMount, 0, len(now)+1)
+	for _, m := range now {
+		if m.Name != name {
+			synced = append(synced, m)
+		} else if want != nil {
+			synced = append(synced, *want)
+			want = nil
+		}
+	}
+	if want != nil {
+		synced = append(synced, *want)
+	}
+	return synced
+}
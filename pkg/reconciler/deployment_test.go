@@ -0,0 +1,276 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	sourcesv1 "knative.dev/eventing/pkg/apis/sources/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	fakedynamicclient "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/sample-source/pkg/apis/samples/v1alpha1"
+	"knative.dev/sample-source/pkg/reconciler/sample/resources"
+)
+
+const (
+	sinkURI  = "http://sink.ns.svc.cluster.local"
+	otherURI = "http://other.ns.svc.cluster.local"
+)
+
+func newBinder(uri string, overrides *duckv1.CloudEventOverrides) *sourcesv1.SinkBinding {
+	sink, _ := apis.ParseURL(uri)
+	return &sourcesv1.SinkBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "source"},
+		Spec: sourcesv1.SinkBindingSpec{
+			SourceSpec: duckv1.SourceSpec{
+				Sink:                duckv1.Destination{URI: sink},
+				CloudEventOverrides: overrides,
+			},
+		},
+	}
+}
+
+// serverDefaults sets the fields the API server defaults on the pod spec of a
+// created Deployment.
+func serverDefaults(spec *corev1.PodSpec) {
+	spec.RestartPolicy = corev1.RestartPolicyAlways
+	if spec.TerminationGracePeriodSeconds == nil {
+		spec.TerminationGracePeriodSeconds = ptr.Int64(corev1.DefaultTerminationGracePeriodSeconds)
+	}
+	spec.DNSPolicy = corev1.DNSClusterFirst
+	spec.SecurityContext = &corev1.PodSecurityContext{}
+	spec.SchedulerName = corev1.DefaultSchedulerName
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+		c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		c.ImagePullPolicy = corev1.PullIfNotPresent
+		for j := range c.Ports {
+			if c.Ports[j].Protocol == "" {
+				c.Ports[j].Protocol = corev1.ProtocolTCP
+			}
+		}
+		if p := c.ReadinessProbe; p != nil {
+			if p.HTTPGet != nil && p.HTTPGet.Scheme == "" {
+				p.HTTPGet.Scheme = corev1.URISchemeHTTP
+			}
+			if p.TimeoutSeconds == 0 {
+				p.TimeoutSeconds = 1
+			}
+			if p.PeriodSeconds == 0 {
+				p.PeriodSeconds = 10
+			}
+			if p.SuccessThreshold == 0 {
+				p.SuccessThreshold = 1
+			}
+			if p.FailureThreshold == 0 {
+				p.FailureThreshold = 3
+			}
+		}
+	}
+	for _, v := range spec.Volumes {
+		if v.Secret != nil && v.Secret.DefaultMode == nil {
+			v.Secret.DefaultMode = ptr.Int32(corev1.SecretVolumeSourceDefaultMode)
+		}
+	}
+}
+
+// webhookVolume is a volume added by an admission webhook.
+var webhookVolume = corev1.Volume{
+	Name: "istio-envoy",
+	VolumeSource: corev1.VolumeSource{
+		EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+	},
+}
+
+// webhookMount mounts webhookVolume.
+var webhookMount = corev1.VolumeMount{
+	Name:      "istio-envoy",
+	MountPath: "/etc/istio/proxy",
+}
+
+// env returns the env var name of spec, nil if there is none.
+func env(spec *corev1.PodSpec, name string) *corev1.EnvVar {
+	for i, e := range spec.Containers[0].Env {
+		if e.Name == name {
+			return &spec.Containers[0].Env[i]
+		}
+	}
+	return nil
+}
+
+func TestPodSpecSync(t *testing.T) {
+	overrides := &duckv1.CloudEventOverrides{Extensions: map[string]string{"team": "a"}}
+
+	testCases := map[string]struct {
+		// noSigning omits the signing secret from the expected Deployment.
+		noSigning bool
+		binder    *sourcesv1.SinkBinding
+		// drift changes the Deployment read from the API server.
+		drift func(*corev1.PodSpec)
+		// want changes the created Deployment into the synced one.
+		want       func(*corev1.PodSpec)
+		wantUpdate bool
+	}{
+		"server defaulted": {
+			binder: newBinder(sinkURI, overrides),
+		},
+		"volumes added by webhooks": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.Volumes = append(spec.Volumes, webhookVolume)
+				spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, webhookMount)
+			},
+			want: func(spec *corev1.PodSpec) {
+				spec.Volumes = append(spec.Volumes, webhookVolume)
+				spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, webhookMount)
+			},
+		},
+		"signing secret removed": {
+			noSigning: true,
+			binder:    newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.Volumes = append(spec.Volumes, webhookVolume)
+				spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, webhookMount)
+			},
+			want: func(spec *corev1.PodSpec) {
+				spec.Volumes = []corev1.Volume{webhookVolume}
+				spec.Containers[0].VolumeMounts = []corev1.VolumeMount{webhookMount}
+				env := spec.Containers[0].Env[:0]
+				for _, e := range spec.Containers[0].Env {
+					if e.Name != "SIGNING_SECRET_FILE" && e.Name != "SIGNATURE_HEADER" {
+						env = append(env, e)
+					}
+				}
+				spec.Containers[0].Env = env
+			},
+			wantUpdate: true,
+		},
+		"signing secret volume changed": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.Volumes[0].Secret.SecretName = "other"
+				spec.Containers[0].VolumeMounts[0].MountPath = "/other"
+			},
+			wantUpdate: true,
+		},
+		"readiness probe missing": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.Containers[0].Ports = nil
+				spec.Containers[0].ReadinessProbe = nil
+			},
+			wantUpdate: true,
+		},
+		"termination grace period changed": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.TerminationGracePeriodSeconds = ptr.Int64(10)
+			},
+			wantUpdate: true,
+		},
+		"env drift": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				env(spec, "INTERVAL").Value = "1m"
+				spec.Containers[0].Env = append(spec.Containers[0].Env, corev1.EnvVar{Name: "EXTRA", Value: "value"})
+			},
+			wantUpdate: true,
+		},
+		"sink env vars removed": {
+			binder: newBinder(sinkURI, overrides),
+			drift: func(spec *corev1.PodSpec) {
+				spec.Containers[0].Env = spec.Containers[0].Env[:len(spec.Containers[0].Env)-2]
+			},
+			wantUpdate: true,
+		},
+		"sink changed": {
+			binder: newBinder(otherURI, overrides),
+			want: func(spec *corev1.PodSpec) {
+				env(spec, "K_SINK").Value = otherURI
+			},
+			wantUpdate: true,
+		},
+		"overrides changed": {
+			binder: newBinder(sinkURI, nil),
+			want: func(spec *corev1.PodSpec) {
+				env(spec, "K_CE_OVERRIDES").Value = ""
+			},
+			wantUpdate: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ctx := logtesting.TestContextWithLogger(t)
+			ctx, _ = fakedynamicclient.With(ctx, runtime.NewScheme())
+			ctx = addressable.WithDuck(ctx)
+			ctx = sourcesv1.WithURIResolver(ctx, resolver.NewURIResolver(ctx, func(types.NamespacedName) {}))
+
+			src := &v1alpha1.SampleSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "source", UID: "1234"},
+			}
+			src.Spec.Interval = "10s"
+			src.Spec.Signing = &v1alpha1.SigningSpec{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "signing"},
+					Key:                  "key",
+				},
+			}
+			args := &resources.ReceiveAdapterArgs{Image: "image", Source: src, EventSource: "source"}
+
+			// The Deployment created by the controller with the original
+			// binder, as read back from the API server.
+			created := resources.MakeReceiveAdapter(args).Spec.Template.Spec
+			syncSink(ctx, newBinder(sinkURI, overrides), created)
+			serverDefaults(&created)
+
+			if tc.noSigning {
+				src.Spec.Signing = nil
+			}
+			expected := resources.MakeReceiveAdapter(args).Spec.Template.Spec
+
+			now := created.DeepCopy()
+			if tc.drift != nil {
+				tc.drift(now)
+			}
+			want := created.DeepCopy()
+			if tc.want != nil {
+				tc.want(want)
+			}
+
+			if got := podSpecSync(ctx, tc.binder, expected, now, []string{resources.SigningSecretVolume}); got != tc.wantUpdate {
+				t.Errorf("podSpecSync() = %v, want %v", got, tc.wantUpdate)
+			}
+			if diff := cmp.Diff(want, now); diff != "" {
+				t.Errorf("synced pod spec (-want, +got) = %s", diff)
+			}
+		})
+	}
+}
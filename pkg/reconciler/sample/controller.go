@@ -28,28 +28,37 @@ import (
 
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection/clients/dynamicclient"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/resolver"
 
 	"knative.dev/sample-source/pkg/reconciler"
+	"knative.dev/sample-source/pkg/reconciler/sample/resources"
 
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
-	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment/filtered"
 	samplesourceinformer "knative.dev/sample-source/pkg/client/injection/informers/samples/v1alpha1/samplesource"
 	"knative.dev/sample-source/pkg/client/injection/reconciler/samples/v1alpha1/samplesource"
 )
 
 // NewController initializes the controller and is called by the generated code
 // Registers event handlers to enqueue events
+//
+// The context must carry resources.LabelSelector as a filtered informer
+// selector, see filteredinformerfactory.WithSelectors.
 func NewController(
 	ctx context.Context,
 	cmw configmap.Watcher,
 ) *controller.Impl {
-	deploymentInformer := deploymentinformer.Get(ctx)
+	deploymentInformer := deploymentinformer.Get(ctx, resources.LabelSelector)
 	sampleSourceInformer := samplesourceinformer.Get(ctx)
 
 	r := &Reconciler{
-		dr: &reconciler.DeploymentReconciler{KubeClientSet: kubeclient.Get(ctx)},
+		dr: &reconciler.DeploymentReconciler{
+			KubeClientSet: kubeclient.Get(ctx),
+			OwnedVolumes:  []string{resources.SigningSecretVolume},
+		},
+		dynamicClientSet: dynamicclient.Get(ctx),
 		// Config accessor takes care of tracing/config/logging config propagation to the receive adapter
 		configAccessor: reconcilersource.WatchConfigurations(ctx, "sample-source", cmw),
 	}
@@ -60,6 +69,7 @@ func NewController(
 	impl := samplesource.NewImpl(ctx, r)
 
 	r.sinkResolver = resolver.NewURIResolver(ctx, impl.EnqueueKey)
+	r.enqueueAfter = impl.EnqueueAfter
 
 	logging.FromContext(ctx).Info("Setting up event handlers")
 
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sample
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	filteredinformerfactory "knative.dev/pkg/client/injection/kube/informers/factory/filtered"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/tracing/config"
+
+	"knative.dev/sample-source/pkg/reconciler/sample/resources"
+
+	// Fake injection clients and informers
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	fakedeploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment/filtered/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/factory/filtered/fake"
+	_ "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	. "knative.dev/pkg/reconciler/testing"
+	_ "knative.dev/sample-source/pkg/client/injection/informers/samples/v1alpha1/samplesource/fake"
+)
+
+func setupFakeContext(t *testing.T) context.Context {
+	ctx := logtesting.TestContextWithLogger(t)
+	ctx = filteredinformerfactory.WithSelectors(ctx, resources.LabelSelector)
+	ctx, _ = injection.Fake.SetupInformers(ctx, &rest.Config{})
+	return addressable.WithDuck(ctx)
+}
+
+func newConfigWatcher() configmap.Watcher {
+	return configmap.NewStaticWatcher(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metrics.ConfigMapName(),
+			Namespace: "knative-eventing",
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      logging.ConfigMapName(),
+			Namespace: "knative-eventing",
+		},
+	}, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      config.ConfigName,
+			Namespace: "knative-eventing",
+		},
+	})
+}
+
+func TestNew(t *testing.T) {
+	ctx := setupFakeContext(t)
+	os.Setenv("METRICS_DOMAIN", "knative.dev/eventing")
+	os.Setenv("SAMPLE_SOURCE_RA_IMAGE", "knative.dev/example")
+
+	if c := NewController(ctx, newConfigWatcher()); c == nil {
+		t.Fatal("Expected NewController to return a non-nil value")
+	}
+}
+
+// TestDeploymentInformerScope checks that the Deployment cache only holds the
+// receive adapters created by this controller. With 5000 unrelated Deployments
+// in the cluster, an unscoped informer would cache all 5003 objects.
+func TestDeploymentInformerScope(t *testing.T) {
+	const unrelated, owned = 5000, 3
+
+	ctx, cancel := context.WithCancel(setupFakeContext(t))
+	kc := fakekubeclient.Get(ctx)
+
+	for i := 0; i < unrelated; i++ {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace: fmt.Sprintf("ns-%d", i),
+			Name:      "unrelated",
+			Labels:    map[string]string{"app": "unrelated"},
+		}}
+		if _, err := kc.AppsV1().Deployments(d.Namespace).Create(ctx, d, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+	}
+	for i := 0; i < owned; i++ {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Namespace: fmt.Sprintf("ns-%d", i),
+			Name:      "samplesource",
+			Labels:    resources.Labels(fmt.Sprint("source-", i)),
+		}}
+		if _, err := kc.AppsV1().Deployments(d.Namespace).Create(ctx, d, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Create() = %v", err)
+		}
+	}
+
+	informer := fakedeploymentinformer.Get(ctx, resources.LabelSelector)
+	wait, err := RunAndSyncInformers(ctx, informer.Informer())
+	if err != nil {
+		cancel()
+		t.Fatalf("RunAndSyncInformers() = %v", err)
+	}
+	defer func() {
+		cancel()
+		wait()
+	}()
+
+	got, err := informer.Lister().List(labels.Everything())
+	if err != nil {
+		t.Fatalf("List() = %v", err)
+	}
+	if len(got) != owned {
+		t.Errorf("cached Deployments = %d, want %d", len(got), owned)
+	}
+	t.Logf("cached %d of %d Deployments", len(got), unrelated+owned)
+}
@@ -20,11 +20,19 @@ const (
 	// controllerAgentName is the string used by this controller to identify
 	// itself when creating events.
 	controllerAgentName = "sample-source-controller"
+
+	sourceLabelKey     = "knative-eventing-source"
+	sourceNameLabelKey = "knative-eventing-source-name"
+
+	// LabelSelector selects the resources created by this controller. It is
+	// used to scope the informers of child resources, so that the controller
+	// does not cache every such resource in the cluster.
+	LabelSelector = sourceLabelKey + "=" + controllerAgentName
 )
 
 func Labels(name string) map[string]string {
 	return map[string]string{
-		"knative-eventing-source":      controllerAgentName,
-		"knative-eventing-source-name": name,
+		sourceLabelKey:     controllerAgentName,
+		sourceNameLabelKey: name,
 	}
 }
@@ -18,11 +18,16 @@ package resources
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/ptr"
 
 	"knative.dev/sample-source/pkg/apis/samples/v1alpha1"
 )
@@ -35,12 +40,48 @@ type ReceiveAdapterArgs struct {
 	Source         *v1alpha1.SampleSource
 	EventSource    string
 	AdditionalEnvs []corev1.EnvVar
+
+	// AuditSinkURI is the resolved audit sink, nil if auditing is disabled.
+	AuditSinkURI *apis.URL
+
+	// SecretsInEnv projects secrets into environment variables instead of
+	// mounting them as files. It is kept for compatibility and will be
+	// removed in the next release.
+	SecretsInEnv bool
 }
 
+// SigningSecretVolume is the volume the signing secret is mounted from.
+const SigningSecretVolume = "signing-secret"
+
+const (
+	// signingSecretDir is the directory the signing secret is mounted in.
+	signingSecretDir = "/var/run/secrets/samplesource/signing"
+	// signingSecretPath is the file name of the signing secret.
+	signingSecretPath = "secret"
+
+	// httpPortName is the name of the port of the adapter HTTP endpoints.
+	httpPortName = "http"
+	// httpPort is the port the adapter HTTP endpoints are served on.
+	httpPort = 8080
+
+	// stopGracePeriod is the time the receive adapter is given to stop on
+	// top of its drain timeout. With the default drain timeout, the
+	// termination grace period is the Kubernetes default.
+	stopGracePeriod = 25 * time.Second
+)
+
 // MakeReceiveAdapter generates (but does not insert into K8s) the Receive Adapter Deployment for
 // Sample sources.
 func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
 	replicas := int32(1)
+	env := makeEnv(args.EventSource, &args.Source.Spec, args.SecretsInEnv)
+	if args.AuditSinkURI != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "AUDIT_SINK",
+			Value: args.AuditSinkURI.String(),
+		})
+	}
+	volumes, mounts := makeVolumes(&args.Source.Spec, args.SecretsInEnv)
 	return &v1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: args.Source.Namespace,
@@ -60,25 +101,31 @@ func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
 					Labels: args.Labels,
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: args.Source.Spec.ServiceAccountName,
+					ServiceAccountName:            args.Source.Spec.ServiceAccountName,
+					TerminationGracePeriodSeconds: makeTerminationGracePeriod(&args.Source.Spec),
 					Containers: []corev1.Container{
 						{
-							Name:  "receive-adapter",
-							Image: args.Image,
-							Env: append(
-								makeEnv(args.EventSource, &args.Source.Spec),
-								args.AdditionalEnvs...,
-							),
+							Name:         "receive-adapter",
+							Image:        args.Image,
+							Env:          append(env, args.AdditionalEnvs...),
+							VolumeMounts: mounts,
+							Ports: []corev1.ContainerPort{{
+								Name:          httpPortName,
+								ContainerPort: httpPort,
+								Protocol:      corev1.ProtocolTCP,
+							}},
+							ReadinessProbe: makeReadinessProbe(),
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
 	}
 }
 
-func makeEnv(eventSource string, spec *v1alpha1.SampleSourceSpec) []corev1.EnvVar {
-	return []corev1.EnvVar{{
+func makeEnv(eventSource string, spec *v1alpha1.SampleSourceSpec, secretsInEnv bool) []corev1.EnvVar {
+	env := []corev1.EnvVar{{
 		Name:  "EVENT_SOURCE",
 		Value: eventSource,
 	}, {
@@ -88,18 +135,184 @@ func makeEnv(eventSource string, spec *v1alpha1.SampleSourceSpec) []corev1.EnvVa
 		Name: "NAMESPACE",
 		ValueFrom: &corev1.EnvVarSource{
 			FieldRef: &corev1.ObjectFieldSelector{
-				FieldPath: "metadata.namespace",
+				APIVersion: "v1",
+				FieldPath:  "metadata.namespace",
 			},
 		},
 	}, {
 		Name: "NAME",
 		ValueFrom: &corev1.EnvVarSource{
 			FieldRef: &corev1.ObjectFieldSelector{
-				FieldPath: "metadata.name",
+				APIVersion: "v1",
+				FieldPath:  "metadata.name",
 			},
 		},
 	}, {
 		Name:  "METRICS_DOMAIN",
 		Value: "knative.dev/eventing",
+	}, {
+		Name:  "HTTP_PORT",
+		Value: strconv.Itoa(httpPort),
 	}}
+
+	if spec.Signing != nil {
+		if secretsInEnv {
+			env = append(env, corev1.EnvVar{
+				Name: "SIGNING_SECRET",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: spec.Signing.SecretKeyRef,
+				},
+			})
+		} else {
+			env = append(env, corev1.EnvVar{
+				Name:  "SIGNING_SECRET_FILE",
+				Value: signingSecretDir + "/" + signingSecretPath,
+			})
+		}
+		env = append(env, corev1.EnvVar{
+			Name:  "SIGNATURE_HEADER",
+			Value: spec.Signing.Header,
+		})
+	}
+
+	if spec.Compression != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "COMPRESSION",
+			Value: "true",
+		}, corev1.EnvVar{
+			Name:  "COMPRESSION_THRESHOLD",
+			Value: strconv.Itoa(int(spec.Compression.Threshold)),
+		}, corev1.EnvVar{
+			Name:  "COMPRESSION_ASSUME_SUPPORTED",
+			Value: strconv.FormatBool(spec.Compression.AssumeSupported),
+		})
+	}
+
+	if spec.RecentEvents != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "RECENT_EVENTS_SIZE",
+			Value: strconv.Itoa(int(spec.RecentEvents.Size)),
+		}, corev1.EnvVar{
+			Name:  "RECENT_EVENTS_MAX_AGE",
+			Value: spec.RecentEvents.MaxAge,
+		})
+	}
+
+	if sf := spec.SinkFailure; sf != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "SINK_FAILURE_POLICY",
+			Value: string(sf.Policy),
+		})
+		if sf.BufferSize > 0 {
+			env = append(env, corev1.EnvVar{
+				Name:  "SINK_BUFFER_SIZE",
+				Value: strconv.Itoa(int(sf.BufferSize)),
+			})
+		}
+		if sf.FallbackURI != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "SINK_FALLBACK_URI",
+				Value: sf.FallbackURI.String(),
+			})
+		}
+		if sf.DrainTimeout != "" {
+			env = append(env, corev1.EnvVar{
+				Name:  "SINK_DRAIN_TIMEOUT",
+				Value: sf.DrainTimeout,
+			})
+		}
+	}
+
+	if spec.FailurePolicy != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "FAILURE_POLICY",
+			Value: string(spec.FailurePolicy),
+		})
+	}
+
+	if spec.InvalidEventPolicy != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "INVALID_EVENT_POLICY",
+			Value: string(spec.InvalidEventPolicy),
+		})
+	}
+
+	if spec.SLOTarget != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "SLO_TARGET",
+			Value: spec.SLOTarget,
+		})
+	}
+
+	return env
+}
+
+// makeTerminationGracePeriod returns the termination grace period of the
+// receive adapter, long enough for it to drain its buffered events.
+func makeTerminationGracePeriod(spec *v1alpha1.SampleSourceSpec) *int64 {
+	return ptr.Int64(int64((StopTimeout(spec) + time.Second - 1) / time.Second))
+}
+
+// StopTimeout returns how long a receive adapter being replaced may take to
+// drain its buffered events and stop.
+func StopTimeout(spec *v1alpha1.SampleSourceSpec) time.Duration {
+	drainTimeout := v1alpha1.DefaultDrainTimeout
+	if sf := spec.SinkFailure; sf != nil && sf.DrainTimeout != "" {
+		// The drain timeout is validated by the webhook.
+		if d, err := time.ParseDuration(sf.DrainTimeout); err == nil {
+			drainTimeout = d
+		}
+	}
+	return drainTimeout + stopGracePeriod
+}
+
+// makeReadinessProbe returns the probe of the adapter readiness endpoint. The
+// fields the API server defaults are set explicitly, so that the probe read
+// back matches.
+func makeReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/readyz",
+				Port:   intstr.FromString(httpPortName),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		TimeoutSeconds:   1,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+}
+
+// makeVolumes returns the volumes the secrets referenced by spec are mounted
+// from, and their mounts in the receive adapter container.
+func makeVolumes(spec *v1alpha1.SampleSourceSpec, secretsInEnv bool) ([]corev1.Volume, []corev1.VolumeMount) {
+	if secretsInEnv || spec.Signing == nil {
+		return nil, nil
+	}
+
+	ref := spec.Signing.SecretKeyRef
+	volume := corev1.Volume{
+		Name: SigningSecretVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: ref.Name,
+				Items: []corev1.KeyToPath{{
+					Key:  ref.Key,
+					Path: signingSecretPath,
+				}},
+				// Set explicitly, or the server defaulted 0644 is a diff
+				// on every reconcile.
+				DefaultMode: ptr.Int32(0400),
+				Optional:    ref.Optional,
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      SigningSecretVolume,
+		MountPath: signingSecretDir,
+		ReadOnly:  true,
+	}
+	return []corev1.Volume{volume}, []corev1.VolumeMount{mount}
 }
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/sample-source/pkg/apis/samples/v1alpha1"
+)
+
+func TestMakeReceiveAdapterSigningSecret(t *testing.T) {
+	secretRef := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "signing"},
+		Key:                  "key",
+	}
+	src := &v1alpha1.SampleSource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "source", UID: "1234"},
+		Spec: v1alpha1.SampleSourceSpec{
+			Interval: "10s",
+			Signing: &v1alpha1.SigningSpec{
+				SecretKeyRef: secretRef,
+				Header:       "X-Signature-256",
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		secretsInEnv bool
+		wantEnv      corev1.EnvVar
+		wantVolumes  []corev1.Volume
+		wantMounts   []corev1.VolumeMount
+	}{
+		"file": {
+			wantEnv: corev1.EnvVar{
+				Name:  "SIGNING_SECRET_FILE",
+				Value: "/var/run/secrets/samplesource/signing/secret",
+			},
+			wantVolumes: []corev1.Volume{{
+				Name: "signing-secret",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName:  "signing",
+						Items:       []corev1.KeyToPath{{Key: "key", Path: "secret"}},
+						DefaultMode: ptr.Int32(0400),
+					},
+				},
+			}},
+			wantMounts: []corev1.VolumeMount{{
+				Name:      "signing-secret",
+				MountPath: "/var/run/secrets/samplesource/signing",
+				ReadOnly:  true,
+			}},
+		},
+		"env compatibility": {
+			secretsInEnv: true,
+			wantEnv: corev1.EnvVar{
+				Name:      "SIGNING_SECRET",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secretRef},
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			d := MakeReceiveAdapter(&ReceiveAdapterArgs{
+				Image:        "image",
+				Labels:       Labels(src.Name),
+				Source:       src,
+				EventSource:  "ns/source",
+				SecretsInEnv: tc.secretsInEnv,
+			})
+			spec := d.Spec.Template.Spec
+			c := spec.Containers[0]
+
+			var got *corev1.EnvVar
+			for i, e := range c.Env {
+				if e.Name == tc.wantEnv.Name {
+					got = &c.Env[i]
+				}
+				if !tc.secretsInEnv && e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+					t.Errorf("env %s references a secret in file mode", e.Name)
+				}
+			}
+			if got == nil {
+				t.Fatalf("env %s not found in %v", tc.wantEnv.Name, c.Env)
+			}
+			if diff := cmp.Diff(tc.wantEnv, *got); diff != "" {
+				t.Error("unexpected env (-want, +got) =", diff)
+			}
+			if diff := cmp.Diff(tc.wantVolumes, spec.Volumes); diff != "" {
+				t.Error("unexpected volumes (-want, +got) =", diff)
+			}
+			if diff := cmp.Diff(tc.wantMounts, c.VolumeMounts); diff != "" {
+				t.Error("unexpected volume mounts (-want, +got) =", diff)
+			}
+		})
+	}
+}
+
+func TestMakeReceiveAdapterReadinessProbe(t *testing.T) {
+	src := &v1alpha1.SampleSource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "source", UID: "1234"},
+		Spec:       v1alpha1.SampleSourceSpec{Interval: "10s"},
+	}
+	c := MakeReceiveAdapter(&ReceiveAdapterArgs{Source: src}).Spec.Template.Spec.Containers[0]
+
+	probe := c.ReadinessProbe
+	if probe == nil || probe.HTTPGet == nil {
+		t.Fatalf("ReadinessProbe = %v, want an HTTP GET probe", probe)
+	}
+	if got, want := probe.HTTPGet.Path, "/readyz"; got != want {
+		t.Errorf("probe path = %q, want %q", got, want)
+	}
+	ports := make(map[string]int32, len(c.Ports))
+	for _, p := range c.Ports {
+		ports[p.Name] = p.ContainerPort
+	}
+	port, ok := ports[probe.HTTPGet.Port.String()]
+	if !ok {
+		t.Fatalf("probe port %q is not a container port of %v", probe.HTTPGet.Port.String(), c.Ports)
+	}
+	// The adapter serves the probe on HTTP_PORT.
+	wantEnv := corev1.EnvVar{Name: "HTTP_PORT", Value: strconv.Itoa(int(port))}
+	for _, e := range c.Env {
+		if e.Name == wantEnv.Name {
+			if diff := cmp.Diff(wantEnv, e); diff != "" {
+				t.Error("unexpected env (-want, +got) =", diff)
+			}
+			return
+		}
+	}
+	t.Errorf("env %s not found in %v", wantEnv.Name, c.Env)
+}
+
+func TestMakeReceiveAdapterTerminationGracePeriod(t *testing.T) {
+	testCases := map[string]struct {
+		sinkFailure *v1alpha1.SinkFailureSpec
+		want        int64
+	}{
+		"default drain timeout": {
+			want: 30,
+		},
+		"drain timeout": {
+			sinkFailure: &v1alpha1.SinkFailureSpec{
+				Policy:       v1alpha1.SinkFailurePolicyBuffer,
+				DrainTimeout: "1m",
+			},
+			want: 85,
+		},
+		"partial seconds are rounded up": {
+			sinkFailure: &v1alpha1.SinkFailureSpec{
+				Policy:       v1alpha1.SinkFailurePolicyBuffer,
+				DrainTimeout: "1500ms",
+			},
+			want: 27,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &v1alpha1.SampleSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "source", UID: "1234"},
+				Spec: v1alpha1.SampleSourceSpec{
+					Interval:    "10s",
+					SinkFailure: tc.sinkFailure,
+				},
+			}
+			spec := MakeReceiveAdapter(&ReceiveAdapterArgs{Source: src}).Spec.Template.Spec
+			if spec.TerminationGracePeriodSeconds == nil {
+				t.Fatal("TerminationGracePeriodSeconds is not set")
+			}
+			if got := *spec.TerminationGracePeriodSeconds; got != tc.want {
+				t.Errorf("TerminationGracePeriodSeconds = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
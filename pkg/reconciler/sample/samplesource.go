@@ -18,11 +18,20 @@ package sample
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	// k8s.io imports
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 
 	// knative.dev/pkg imports
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
@@ -37,14 +46,41 @@ import (
 	"knative.dev/sample-source/pkg/reconciler/sample/resources"
 )
 
+// Reasons for the SinkProvided condition being False.
+const (
+	// sinkNotFound is the reason when the referenced sink does not exist.
+	sinkNotFound = "SinkNotFound"
+	// sinkNotAddressable is the reason when the sink exists but has no
+	// address, or the destination is not valid.
+	sinkNotAddressable = "SinkNotAddressable"
+	// sinkAccessDenied is the reason when the controller is not allowed to
+	// read the referenced sink.
+	sinkAccessDenied = "SinkAccessDenied"
+	// sinkResolveFailedTransient is the reason when resolving the sink failed
+	// for a reason expected to go away, it is retried with backoff.
+	sinkResolveFailedTransient = "SinkResolveFailedTransient"
+)
+
 // Reconciler reconciles a SampleSource object
 type Reconciler struct {
 	ReceiveAdapterImage string `envconfig:"SAMPLE_SOURCE_RA_IMAGE" required:"true"`
 
+	// SecretsInEnv passes secrets to receive adapters in environment
+	// variables rather than files, for compatibility with older adapters.
+	SecretsInEnv bool `envconfig:"SAMPLE_SOURCE_SECRETS_IN_ENV"`
+
 	dr *reconciler.DeploymentReconciler
 
+	// dynamicClientSet reads sinks which could not be resolved, to find out
+	// why.
+	dynamicClientSet dynamic.Interface
+
 	sinkResolver *resolver.URIResolver
 
+	// enqueueAfter requeues a SampleSource, so that the end of a sink switch
+	// is reflected in its status.
+	enqueueAfter func(obj interface{}, after time.Duration)
+
 	configAccessor reconcilersource.ConfigAccessor
 }
 
@@ -54,6 +90,22 @@ var _ reconcilersamplesource.Interface = (*Reconciler)(nil)
 // ReconcileKind implements Interface.ReconcileKind.
 func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.SampleSource) pkgreconciler.Event {
 
+	previousSinkURI := src.Status.SinkURI
+	if event := r.resolveSink(ctx, src); event != nil {
+		return event
+	}
+	r.trackSinkSwitch(src, previousSinkURI, time.Now())
+
+	var auditSinkURI *apis.URL
+	if dest := src.Spec.AuditSink; dest != nil {
+		uri, err := r.sinkResolver.URIFromDestinationV1(ctx, *dest, src)
+		if err != nil {
+			// The resolver tracks the audit sink, so this is retried once it changes.
+			return pkgreconciler.NewEvent(corev1.EventTypeWarning, "AuditSinkNotResolved", "Failed to resolve audit sink: %v", err)
+		}
+		auditSinkURI = uri
+	}
+
 	ctx = sourcesv1.WithURIResolver(ctx, r.sinkResolver)
 
 	ra, sb, event := r.dr.ReconcileDeployment(ctx, src, makeSinkBinding(src),
@@ -63,6 +115,8 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, src *v1alpha1.SampleSour
 			Source:         src,
 			Labels:         resources.Labels(src.Name),
 			AdditionalEnvs: r.configAccessor.ToEnvVars(), // Grab config envs for tracing/logging/metrics
+			SecretsInEnv:   r.SecretsInEnv,
+			AuditSinkURI:   auditSinkURI,
 		}),
 	)
 	if ra != nil {
@@ -95,3 +149,74 @@ func makeSinkBinding(src *v1alpha1.SampleSource) *sourcesv1.SinkBinding {
 		},
 	}
 }
+
+// resolveSink resolves the sink of src and reflects the outcome in the
+// SinkProvided condition. Only transient failures are returned as errors, to
+// be retried with backoff. The resolver tracks the referenced sink, so the
+// other failures are retried once the sink changes.
+func (r *Reconciler) resolveSink(ctx context.Context, src *v1alpha1.SampleSource) pkgreconciler.Event {
+	uri, err := r.sinkResolver.URIFromDestinationV1(ctx, src.Spec.Sink, src)
+	if err == nil {
+		src.Status.MarkSink(uri)
+		return nil
+	}
+
+	reason, message := r.classifySinkError(ctx, src.Spec.Sink.Ref, err)
+	src.Status.MarkNoSink(reason, "%s", message)
+	event := pkgreconciler.NewEvent(corev1.EventTypeWarning, reason, "%s", message)
+	if reason == sinkResolveFailedTransient {
+		return fmt.Errorf("failed to resolve sink: %w", event)
+	}
+	return event
+}
+
+// trackSinkSwitch reflects in the SinkSwitched condition whether the receive
+// adapter started with the sink src had before previous is still draining its
+// events, which takes at most its stop timeout. src is requeued for the end of
+// the switch.
+func (r *Reconciler) trackSinkSwitch(src *v1alpha1.SampleSource, previous *apis.URL, now time.Time) {
+	timeout := resources.StopTimeout(&src.Spec)
+	if previous != nil && previous.String() != src.Status.SinkURI.String() {
+		src.Status.MarkSinkSwitching(previous, timeout)
+	}
+
+	cond := src.Status.GetCondition(v1alpha1.SampleConditionSinkSwitched)
+	if cond == nil || !cond.IsUnknown() {
+		return
+	}
+	if left := cond.LastTransitionTime.Inner.Add(timeout).Sub(now); left > 0 {
+		r.enqueueAfter(src, left)
+		return
+	}
+	src.Status.MarkSinkSwitched()
+}
+
+// classifySinkError returns the condition reason and message for err, as
+// returned when resolving a sink referencing ref.
+func (r *Reconciler) classifySinkError(ctx context.Context, ref *duckv1.KReference, err error) (string, string) {
+	if _, ok := err.(apierrors.APIStatus); !ok {
+		// The destination itself is not valid.
+		return sinkNotAddressable, fmt.Sprintf("Sink is not addressable: %v", err)
+	}
+	if apierrors.IsBadRequest(err) && ref != nil {
+		return sinkNotAddressable, fmt.Sprintf("Sink %s %s/%s is not addressable: %v", ref.Kind, ref.Namespace, ref.Name, err)
+	}
+	if !apierrors.IsNotFound(err) || ref == nil {
+		return sinkResolveFailedTransient, fmt.Sprintf("Failed to resolve sink: %v", err)
+	}
+
+	// The resolver reports any failure to get the sink from its informer
+	// cache as not found, ask the API server for the actual reason.
+	gvr, _ := meta.UnsafeGuessKindToResource(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	_, err = r.dynamicClientSet.Resource(gvr).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return sinkResolveFailedTransient, fmt.Sprintf("Sink %s %s/%s is not cached yet", ref.Kind, ref.Namespace, ref.Name)
+	case apierrors.IsNotFound(err):
+		return sinkNotFound, fmt.Sprintf("Sink %s %s/%s does not exist", ref.Kind, ref.Namespace, ref.Name)
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return sinkAccessDenied, fmt.Sprintf("Not allowed to get sink %s %s/%s, check the RBAC permissions of the controller: %v", ref.Kind, ref.Namespace, ref.Name, err)
+	default:
+		return sinkResolveFailedTransient, fmt.Sprintf("Failed to get sink %s %s/%s: %v", ref.Kind, ref.Namespace, ref.Name, err)
+	}
+}
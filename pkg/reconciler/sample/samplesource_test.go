@@ -0,0 +1,234 @@
+/*
+Copyright 2021 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sample
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	fakedynamicclient "knative.dev/pkg/injection/clients/dynamicclient/fake"
+	logtesting "knative.dev/pkg/logging/testing"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/resolver"
+
+	"knative.dev/sample-source/pkg/apis/samples/v1alpha1"
+)
+
+const (
+	testNS     = "testnamespace"
+	brokerName = "default"
+)
+
+var brokerResource = schema.GroupResource{Group: "eventing.knative.dev", Resource: "brokers"}
+
+func newBroker(address string) *unstructured.Unstructured {
+	b := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "eventing.knative.dev/v1",
+		"kind":       "Broker",
+		"metadata": map[string]interface{}{
+			"namespace": testNS,
+			"name":      brokerName,
+		},
+	}}
+	if address != "" {
+		b.Object["status"] = map[string]interface{}{
+			"address": map[string]interface{}{"url": address},
+		}
+	}
+	return b
+}
+
+// getFails makes getting brokers with the dynamic client fail with err.
+func getFails(err error) k8stesting.ReactionFunc {
+	return func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, err
+	}
+}
+
+func TestResolveSink(t *testing.T) {
+	testCases := map[string]struct {
+		objects     []runtime.Object
+		getReaction k8stesting.ReactionFunc
+		wantReason  string
+		wantRequeue bool
+	}{
+		"resolved": {
+			objects: []runtime.Object{newBroker("http://broker.example.com")},
+		},
+		"not found": {
+			wantReason: sinkNotFound,
+		},
+		"not addressable": {
+			objects:    []runtime.Object{newBroker("")},
+			wantReason: sinkNotAddressable,
+		},
+		"access denied": {
+			getReaction: getFails(apierrors.NewForbidden(brokerResource, brokerName, nil)),
+			wantReason:  sinkAccessDenied,
+		},
+		"transient": {
+			getReaction: getFails(apierrors.NewServerTimeout(brokerResource, "get", 1)),
+			wantReason:  sinkResolveFailedTransient,
+			wantRequeue: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ctx := logtesting.TestContextWithLogger(t)
+			ctx, dc := fakedynamicclient.With(ctx, runtime.NewScheme(), tc.objects...)
+			if tc.getReaction != nil {
+				dc.PrependReactor("get", "brokers", tc.getReaction)
+			}
+			ctx = addressable.WithDuck(ctx)
+
+			r := &Reconciler{
+				dynamicClientSet: dc,
+				sinkResolver:     resolver.NewURIResolver(ctx, func(types.NamespacedName) {}),
+			}
+			src := &v1alpha1.SampleSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNS, Name: "source"},
+			}
+			src.Spec.Sink = duckv1.Destination{Ref: &duckv1.KReference{
+				APIVersion: "eventing.knative.dev/v1",
+				Kind:       "Broker",
+				Namespace:  testNS,
+				Name:       brokerName,
+			}}
+			src.Status.InitializeConditions()
+
+			event := r.resolveSink(ctx, src)
+
+			cond := src.Status.GetCondition(v1alpha1.SampleConditionSinkProvided)
+			if tc.wantReason == "" {
+				if event != nil {
+					t.Errorf("resolveSink() = %v, want nil", event)
+				}
+				if !cond.IsTrue() {
+					t.Errorf("SinkProvided = %v, want True", cond)
+				}
+				return
+			}
+
+			if !cond.IsFalse() || cond.Reason != tc.wantReason {
+				t.Errorf("SinkProvided = %v, want False with reason %s", cond, tc.wantReason)
+			}
+			var re *pkgreconciler.ReconcilerEvent
+			if !pkgreconciler.EventAs(event, &re) || re.EventType != corev1.EventTypeWarning || re.Reason != tc.wantReason {
+				t.Errorf("resolveSink() = %v, want a warning event with reason %s", event, tc.wantReason)
+			}
+			// Bare events are recorded without requeueing the key.
+			_, isEvent := event.(*pkgreconciler.ReconcilerEvent)
+			if requeue := !isEvent; requeue != tc.wantRequeue {
+				t.Errorf("requeue = %v, want %v", requeue, tc.wantRequeue)
+			}
+		})
+	}
+}
+
+func TestTrackSinkSwitch(t *testing.T) {
+	oldSink := apis.HTTP("old.example.com")
+	newSink := apis.HTTP("new.example.com")
+	now := time.Now()
+	// The stop timeout of a receive adapter with the default drain timeout.
+	const timeout = 30 * time.Second
+
+	testCases := map[string]struct {
+		previous *apis.URL
+		// switchedAgo is how long ago the sink changed from oldSink, if it
+		// is being switched already.
+		switchedAgo  time.Duration
+		wantStatus   corev1.ConditionStatus
+		wantPrevious *apis.URL
+		wantRequeue  time.Duration
+	}{
+		"first sink": {},
+		"unchanged": {
+			previous: newSink,
+		},
+		"switched": {
+			previous:     oldSink,
+			wantStatus:   corev1.ConditionUnknown,
+			wantPrevious: oldSink,
+			wantRequeue:  timeout,
+		},
+		"draining": {
+			previous:     newSink,
+			switchedAgo:  10 * time.Second,
+			wantStatus:   corev1.ConditionUnknown,
+			wantPrevious: oldSink,
+			wantRequeue:  timeout - 10*time.Second,
+		},
+		"drained": {
+			previous:    newSink,
+			switchedAgo: timeout,
+			wantStatus:  corev1.ConditionTrue,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var requeue time.Duration
+			r := &Reconciler{
+				enqueueAfter: func(_ interface{}, after time.Duration) { requeue = after },
+			}
+			src := &v1alpha1.SampleSource{}
+			src.Status.InitializeConditions()
+			if tc.switchedAgo > 0 {
+				src.Status.MarkSinkSwitching(oldSink, timeout)
+				cond := src.Status.GetCondition(v1alpha1.SampleConditionSinkSwitched)
+				cond.LastTransitionTime = apis.VolatileTime{Inner: metav1.NewTime(now.Add(-tc.switchedAgo))}
+				src.Status.SetConditions([]apis.Condition{*cond})
+			}
+			src.Status.MarkSink(newSink)
+
+			r.trackSinkSwitch(src, tc.previous, now)
+
+			cond := src.Status.GetCondition(v1alpha1.SampleConditionSinkSwitched)
+			if tc.wantStatus == "" {
+				if cond != nil {
+					t.Errorf("SinkSwitched = %v, want unset", cond)
+				}
+			} else if cond == nil || cond.Status != tc.wantStatus {
+				t.Errorf("SinkSwitched = %v, want %s", cond, tc.wantStatus)
+			}
+			if got := src.Status.PreviousSinkURI; got.String() != tc.wantPrevious.String() {
+				t.Errorf("PreviousSinkURI = %v, want %v", got, tc.wantPrevious)
+			}
+			// The condition time has a second precision.
+			if requeue.Round(time.Second) != tc.wantRequeue {
+				t.Errorf("requeued after %v, want %v", requeue, tc.wantRequeue)
+			}
+			// A switch does not affect readiness.
+			if cond != nil && cond.Severity != apis.ConditionSeverityInfo {
+				t.Errorf("SinkSwitched severity = %q, want %q", cond.Severity, apis.ConditionSeverityInfo)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version sets the minimum Kubernetes version the controller and the
+// webhook check for on startup.
+package version
+
+import (
+	"os"
+
+	"knative.dev/pkg/version"
+)
+
+// MinimumKubernetesVersion replaces the minimum Kubernetes version compiled
+// into knative.dev/pkg when set at link time with
+// -ldflags "-X knative.dev/sample-source/pkg/version.MinimumKubernetesVersion=v1.20.0".
+var MinimumKubernetesVersion = ""
+
+// getMinimumVersion returns the minimum Kubernetes version set by the
+// KUBERNETES_MIN_VERSION env var, else by MinimumKubernetesVersion. It is
+// empty if the compiled default applies.
+func getMinimumVersion() string {
+	if v := os.Getenv(version.KubernetesMinVersionKey); v != "" {
+		return v
+	}
+	return MinimumKubernetesVersion
+}
+
+// SetMinimumVersion makes the version check of knative.dev/pkg require the
+// minimum Kubernetes version of getMinimumVersion. It must be called before
+// the shared main starts.
+func SetMinimumVersion() error {
+	if v := getMinimumVersion(); v != "" {
+		return os.Setenv(version.KubernetesMinVersionKey, v)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 The Knative Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+	pkgversion "knative.dev/pkg/version"
+)
+
+func TestSetMinimumVersion(t *testing.T) {
+	// The compiled default of knative.dev/pkg is v1.18.0.
+	cluster := &fakediscovery.FakeDiscovery{
+		Fake:               &k8stesting.Fake{},
+		FakedServerVersion: &version.Info{GitVersion: "v1.19.0"},
+	}
+
+	tests := map[string]struct {
+		env     string
+		build   string
+		want    string
+		wantErr bool
+	}{
+		"compiled default": {
+			want: "",
+		},
+		"build time version": {
+			build:   "v1.20.0",
+			want:    "v1.20.0",
+			wantErr: true,
+		},
+		"env var over build time version": {
+			env:   "v1.19.0",
+			build: "v1.20.0",
+			want:  "v1.19.0",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer os.Unsetenv(pkgversion.KubernetesMinVersionKey)
+			if tc.env != "" {
+				os.Setenv(pkgversion.KubernetesMinVersionKey, tc.env)
+			} else {
+				os.Unsetenv(pkgversion.KubernetesMinVersionKey)
+			}
+			old := MinimumKubernetesVersion
+			MinimumKubernetesVersion = tc.build
+			defer func() { MinimumKubernetesVersion = old }()
+
+			if got := getMinimumVersion(); got != tc.want {
+				t.Errorf("getMinimumVersion() = %q, want %q", got, tc.want)
+			}
+			if err := SetMinimumVersion(); err != nil {
+				t.Fatalf("SetMinimumVersion() = %v", err)
+			}
+			if err := pkgversion.CheckMinimumVersion(cluster); (err != nil) != tc.wantErr {
+				t.Errorf("CheckMinimumVersion() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}